@@ -0,0 +1,94 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+// Package astro provides astronomical calculations, such as the position
+// of the sun, that are independent of any Lutron processor. The
+// calculations follow NOAA's solar calculator and are accurate to within
+// a fraction of a degree for dates between 1901 and 2099.
+package astro
+
+import (
+	"math"
+	"time"
+)
+
+// Position represents the position of the sun in the sky.
+type Position struct {
+	// Azimuth is measured in degrees clockwise from true north.
+	Azimuth float64 `json:"azimuth"`
+	// Elevation is measured in degrees above the horizon and does
+	// not include any correction for atmospheric refraction.
+	Elevation float64 `json:"elevation"`
+}
+
+func radians(d float64) float64 { return d * math.Pi / 180 }
+func degrees(r float64) float64 { return r * 180 / math.Pi }
+
+// mod returns x modulo y in the range [0, y).
+func mod(x, y float64) float64 {
+	x = math.Mod(x, y)
+	if x < 0 {
+		x += y
+	}
+	return x
+}
+
+// julianCentury returns the number of Julian centuries since J2000.0.
+func julianCentury(t time.Time) float64 {
+	jd := float64(t.UTC().UnixNano())/float64(24*time.Hour) + 2440587.5
+	return (jd - 2451545) / 36525
+}
+
+// solarParameters returns the sun's declination, in degrees, and the
+// equation of time, in minutes, for the specified Julian century.
+func solarParameters(jc float64) (declination, eqTime float64) {
+	meanLong := mod(280.46646+jc*(36000.76983+jc*0.0003032), 360)
+	meanAnomaly := 357.52911 + jc*(35999.05029-0.0001537*jc)
+	eccentricity := 0.016708634 - jc*(0.000042037+0.0000001267*jc)
+	m := radians(meanAnomaly)
+	center := math.Sin(m)*(1.914602-jc*(0.004817+0.000014*jc)) +
+		math.Sin(2*m)*(0.019993-0.000101*jc) +
+		math.Sin(3*m)*0.000289
+	omega := radians(125.04 - 1934.136*jc)
+	apparentLong := meanLong + center - 0.00569 - 0.00478*math.Sin(omega)
+	meanObliquity := 23 + (26+(21.448-jc*(46.815+jc*(0.00059-jc*0.001813)))/60)/60
+	obliquity := radians(meanObliquity + 0.00256*math.Cos(omega))
+	declination = degrees(math.Asin(math.Sin(obliquity) * math.Sin(radians(apparentLong))))
+
+	y := math.Pow(math.Tan(obliquity/2), 2)
+	l0 := radians(meanLong)
+	eqTime = 4 * degrees(y*math.Sin(2*l0)-
+		2*eccentricity*math.Sin(m)+
+		4*eccentricity*y*math.Sin(m)*math.Cos(2*l0)-
+		0.5*y*y*math.Sin(4*l0)-
+		1.25*eccentricity*eccentricity*math.Sin(2*m))
+	return declination, eqTime
+}
+
+// SunPosition returns the position of the sun at the specified time for
+// an observer at the specified latitude and longitude, in degrees, with
+// north and east being positive.
+func SunPosition(t time.Time, latitude, longitude float64) Position {
+	t = t.UTC()
+	declination, eqTime := solarParameters(julianCentury(t))
+	minutes := float64(t.Hour()*60+t.Minute()) + float64(t.Second())/60 + float64(t.Nanosecond())/float64(time.Minute)
+	trueSolarTime := mod(minutes+eqTime+4*longitude, 1440)
+	hourAngle := trueSolarTime/4 - 180
+
+	lat, decl, ha := radians(latitude), radians(declination), radians(hourAngle)
+	cosZenith := math.Sin(lat)*math.Sin(decl) + math.Cos(lat)*math.Cos(decl)*math.Cos(ha)
+	zenith := math.Acos(math.Max(-1, math.Min(1, cosZenith)))
+
+	var azimuth float64
+	if denom := math.Cos(lat) * math.Sin(zenith); math.Abs(denom) > 1e-12 {
+		cosAz := (math.Sin(lat)*math.Cos(zenith) - math.Sin(decl)) / denom
+		az := degrees(math.Acos(math.Max(-1, math.Min(1, cosAz))))
+		if hourAngle > 0 {
+			azimuth = mod(az+180, 360)
+		} else {
+			azimuth = mod(540-az, 360)
+		}
+	}
+	return Position{Azimuth: azimuth, Elevation: 90 - degrees(zenith)}
+}
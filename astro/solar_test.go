@@ -0,0 +1,42 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package astro_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/cosnicolaou/lutron/astro"
+)
+
+func TestSunPosition(t *testing.T) {
+	for i, tc := range []struct {
+		when               time.Time
+		lat, long          float64
+		azimuth, elevation float64
+	}{
+		// Solar noon on the June solstice at 40N, the sun is due south
+		// at 90 - 40 + 23.44 degrees.
+		{time.Date(2024, 6, 21, 12, 1, 48, 0, time.UTC), 40, 0, 180, 73.44},
+		// Solar midnight.
+		{time.Date(2024, 6, 21, 0, 0, 0, 0, time.UTC), 40, 0, 0, -26.56},
+		// Morning at the equator on the equinox, the sun is due east.
+		{time.Date(2024, 3, 20, 9, 0, 0, 0, time.UTC), 0, 0, 90, 43.17},
+		// Noon PDT in Mountain View, California, about 70 minutes before
+		// solar noon.
+		{time.Date(2024, 6, 21, 19, 0, 0, 0, time.UTC), 37.42, -122.08, 127.9, 69.45},
+		{time.Date(2024, 12, 21, 17, 0, 0, 0, time.UTC), 40, -105, 151, 20.8},
+	} {
+		pos := astro.SunPosition(tc.when, tc.lat, tc.long)
+		azDiff := math.Abs(math.Mod(pos.Azimuth-tc.azimuth+540, 360) - 180)
+		if azDiff > 1 {
+			t.Errorf("%v: azimuth: got %v, want %v", i, pos.Azimuth, tc.azimuth)
+		}
+		if math.Abs(pos.Elevation-tc.elevation) > 0.2 {
+			t.Errorf("%v: elevation: got %v, want %v", i, pos.Elevation, tc.elevation)
+		}
+	}
+}
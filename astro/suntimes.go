@@ -0,0 +1,107 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package astro
+
+import (
+	"math"
+	"time"
+)
+
+// Zenith angles, in degrees, that define sunrise/sunset and twilight.
+// Sunrise and sunset allow for atmospheric refraction and the size of
+// the sun's disc.
+const (
+	SunriseZenith  = 90.833
+	CivilZenith    = 96.0
+	NauticalZenith = 102.0
+)
+
+// SunTimes represents the times of sunrise, sunset, twilight and solar
+// noon for a single day. Times for events that do not occur on that day,
+// eg. sunset during polar summer, are zero.
+type SunTimes struct {
+	NauticalDawn time.Time `json:"nautical_dawn"`
+	CivilDawn    time.Time `json:"civil_dawn"`
+	Sunrise      time.Time `json:"sunrise"`
+	Noon         time.Time `json:"noon"`
+	Sunset       time.Time `json:"sunset"`
+	CivilDusk    time.Time `json:"civil_dusk"`
+	NauticalDusk time.Time `json:"nautical_dusk"`
+}
+
+// hourAngle returns the hour angle, in degrees, at which the sun reaches
+// the specified zenith angle and false if it never does.
+func hourAngle(latitude, declination, zenith float64) (float64, bool) {
+	lat, decl := radians(latitude), radians(declination)
+	cosHA := math.Cos(radians(zenith))/(math.Cos(lat)*math.Cos(decl)) - math.Tan(lat)*math.Tan(decl)
+	if cosHA < -1 || cosHA > 1 {
+		return 0, false
+	}
+	return degrees(math.Acos(cosHA)), true
+}
+
+// solarNoon returns solar noon, in minutes since midnight UTC, for the
+// day whose midnight UTC is day.
+func solarNoon(day time.Time, longitude float64) float64 {
+	noon := 720 - 4*longitude
+	for range 2 {
+		_, eqTime := solarParameters(julianCentury(atMinutes(day, noon)))
+		noon = 720 - 4*longitude - eqTime
+	}
+	return noon
+}
+
+func atMinutes(day time.Time, minutes float64) time.Time {
+	return day.Add(time.Duration(minutes * float64(time.Minute)))
+}
+
+// eventTime returns the time, in minutes since midnight UTC, at which the
+// sun reaches the specified zenith angle before (rising) or after solar
+// noon. The declination and equation of time are recomputed for the
+// time of the event to improve accuracy.
+func eventTime(day time.Time, noon, latitude, longitude, zenith float64, rising bool) (float64, bool) {
+	sign := 1.0
+	if rising {
+		sign = -1
+	}
+	when := noon
+	for range 2 {
+		declination, eqTime := solarParameters(julianCentury(atMinutes(day, when)))
+		ha, ok := hourAngle(latitude, declination, zenith)
+		if !ok {
+			return 0, false
+		}
+		when = 720 - 4*(longitude-sign*ha) - eqTime
+	}
+	return when, true
+}
+
+// Sun returns the times of sunrise, sunset, civil and nautical twilight
+// and solar noon on the date of the specified time, in its location, for
+// an observer at the specified latitude and longitude, in degrees, with
+// north and east being positive. The returned times are in the same
+// location as date.
+func Sun(date time.Time, latitude, longitude float64) SunTimes {
+	loc := date.Location()
+	y, m, d := date.Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	noon := solarNoon(day, longitude)
+	at := func(zenith float64, rising bool) time.Time {
+		minutes, ok := eventTime(day, noon, latitude, longitude, zenith, rising)
+		if !ok {
+			return time.Time{}
+		}
+		return atMinutes(day, minutes).Round(time.Second).In(loc)
+	}
+	return SunTimes{
+		NauticalDawn: at(NauticalZenith, true),
+		CivilDawn:    at(CivilZenith, true),
+		Sunrise:      at(SunriseZenith, true),
+		Noon:         atMinutes(day, noon).Round(time.Second).In(loc),
+		Sunset:       at(SunriseZenith, false),
+		CivilDusk:    at(CivilZenith, false),
+		NauticalDusk: at(NauticalZenith, false),
+	}
+}
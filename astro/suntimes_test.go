@@ -0,0 +1,92 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package astro_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cosnicolaou/lutron/astro"
+)
+
+func TestSun(t *testing.T) {
+	mv := time.FixedZone("PDT", -7*3600)
+	nz := time.FixedZone("NZST", 12*3600)
+	at := func(loc *time.Location, y int, m time.Month, d, hh, mm int) time.Time {
+		return time.Date(y, m, d, hh, mm, 0, 0, loc)
+	}
+	for i, tc := range []struct {
+		date      time.Time
+		lat, long float64
+		want      astro.SunTimes
+	}{
+		{at(mv, 2024, 6, 21, 0, 0), 37.42, -122.08, astro.SunTimes{
+			NauticalDawn: at(mv, 2024, 6, 21, 4, 38),
+			CivilDawn:    at(mv, 2024, 6, 21, 5, 17),
+			Sunrise:      at(mv, 2024, 6, 21, 5, 48),
+			Noon:         at(mv, 2024, 6, 21, 13, 10),
+			Sunset:       at(mv, 2024, 6, 21, 20, 33),
+			CivilDusk:    at(mv, 2024, 6, 21, 21, 4),
+			NauticalDusk: at(mv, 2024, 6, 21, 21, 43),
+		}},
+		// The date is taken from the supplied time's location.
+		{at(mv, 2024, 6, 21, 23, 0), 37.42, -122.08, astro.SunTimes{
+			NauticalDawn: at(mv, 2024, 6, 21, 4, 38),
+			CivilDawn:    at(mv, 2024, 6, 21, 5, 17),
+			Sunrise:      at(mv, 2024, 6, 21, 5, 48),
+			Noon:         at(mv, 2024, 6, 21, 13, 10),
+			Sunset:       at(mv, 2024, 6, 21, 20, 33),
+			CivilDusk:    at(mv, 2024, 6, 21, 21, 4),
+			NauticalDusk: at(mv, 2024, 6, 21, 21, 43),
+		}},
+		{at(time.UTC, 2024, 12, 21, 12, 0), 51.5, -0.13, astro.SunTimes{
+			NauticalDawn: at(time.UTC, 2024, 12, 21, 6, 40),
+			CivilDawn:    at(time.UTC, 2024, 12, 21, 7, 24),
+			Sunrise:      at(time.UTC, 2024, 12, 21, 8, 4),
+			Noon:         at(time.UTC, 2024, 12, 21, 11, 59),
+			Sunset:       at(time.UTC, 2024, 12, 21, 15, 54),
+			CivilDusk:    at(time.UTC, 2024, 12, 21, 16, 34),
+			NauticalDusk: at(time.UTC, 2024, 12, 21, 17, 17),
+		}},
+		{at(nz, 2024, 6, 21, 0, 0), -36.85, 174.76, astro.SunTimes{
+			NauticalDawn: at(nz, 2024, 6, 21, 6, 32),
+			CivilDawn:    at(nz, 2024, 6, 21, 7, 5),
+			Sunrise:      at(nz, 2024, 6, 21, 7, 34),
+			Noon:         at(nz, 2024, 6, 21, 12, 23),
+			Sunset:       at(nz, 2024, 6, 21, 17, 12),
+			CivilDusk:    at(nz, 2024, 6, 21, 17, 41),
+			NauticalDusk: at(nz, 2024, 6, 21, 18, 13),
+		}},
+		// The sun does not set at midsummer in Tromsø.
+		{at(time.UTC, 2024, 6, 21, 0, 0), 69.65, 18.96, astro.SunTimes{
+			Noon: at(time.UTC, 2024, 6, 21, 10, 46),
+		}},
+	} {
+		got := astro.Sun(tc.date, tc.lat, tc.long)
+		for _, ev := range []struct {
+			name      string
+			got, want time.Time
+		}{
+			{"nautical dawn", got.NauticalDawn, tc.want.NauticalDawn},
+			{"civil dawn", got.CivilDawn, tc.want.CivilDawn},
+			{"sunrise", got.Sunrise, tc.want.Sunrise},
+			{"noon", got.Noon, tc.want.Noon},
+			{"sunset", got.Sunset, tc.want.Sunset},
+			{"civil dusk", got.CivilDusk, tc.want.CivilDusk},
+			{"nautical dusk", got.NauticalDusk, tc.want.NauticalDusk},
+		} {
+			if ev.got.IsZero() != ev.want.IsZero() {
+				t.Errorf("%v: %v: got %v, want %v", i, ev.name, ev.got, ev.want)
+				continue
+			}
+			if d := ev.got.Sub(ev.want); d < -time.Minute || d > time.Minute {
+				t.Errorf("%v: %v: got %v, want %v", i, ev.name, ev.got, ev.want)
+			}
+			if !ev.got.IsZero() && ev.got.Location() != tc.date.Location() {
+				t.Errorf("%v: %v: got location %v, want %v", i, ev.name, ev.got.Location(), tc.date.Location())
+			}
+		}
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package homeworks
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/automation/net/streamconn"
+	"github.com/cosnicolaou/lutron/astro"
+	"github.com/cosnicolaou/lutron/protocol"
+)
+
+// defaultSunTimesTolerance is the default allowed difference between the
+// processor's sunrise/sunset times and those computed locally.
+const defaultSunTimesTolerance = 5 * time.Minute
+
+// latLongCache caches the location reported by the processor.
+type latLongCache struct {
+	mu        sync.Mutex
+	valid     bool
+	lat, long float64
+}
+
+func (c *latLongCache) get() (float64, float64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lat, c.long, c.valid
+}
+
+func (c *latLongCache) set(lat, long float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lat, c.long, c.valid = lat, long, true
+}
+
+func (c *latLongCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.valid = false
+}
+
+// location returns the latitude and longitude configured for the system
+// or, if none are configured, those reported by the processor. The
+// processor's location is cached so that it need only be queried once.
+func (p *QSProcessor) location(ctx context.Context) (float64, float64, error) {
+	if loc := p.System().Location; loc.Latitude != 0 || loc.Longitude != 0 {
+		return loc.Latitude, loc.Longitude, nil
+	}
+	if lat, long, ok := p.latlong.get(); ok {
+		return lat, long, nil
+	}
+	ctx, sess, err := p.session(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer sess.Release()
+	lat, long, err := protocol.GetLatLong(ctx, sess)
+	if err != nil {
+		return 0, 0, err
+	}
+	p.latlong.set(lat, long)
+	return lat, long, nil
+}
+
+// computeSunTimes computes the sunrise, sunset, twilight and solar noon
+// times locally for today, or for the date (YYYY-MM-DD) specified as an
+// argument, in the host's timezone.
+func (p *QSProcessor) computeSunTimes(ctx context.Context, args devices.OperationArgs) (any, error) {
+	date := time.Now()
+	if len(args.Args) > 0 {
+		var err error
+		if date, err = time.ParseInLocation(time.DateOnly, args.Args[0], time.Local); err != nil {
+			return nil, fmt.Errorf("failed to parse date: %v", err)
+		}
+	}
+	lat, long, err := p.location(ctx)
+	if err != nil {
+		return nil, err
+	}
+	st := astro.Sun(date, lat, long)
+	fmt.Fprintf(args.Writer, "nautical dawn: %v\ncivil dawn: %v\nsunrise: %v\nnoon: %v\nsunset: %v\ncivil dusk: %v\nnautical dusk: %v\n",
+		st.NauticalDawn, st.CivilDawn, st.Sunrise, st.Noon, st.Sunset, st.CivilDusk, st.NauticalDusk)
+	return st, nil
+}
+
+// SunTimesCheck represents the difference between the processor's
+// sunrise and sunset times and those computed locally.
+type SunTimesCheck struct {
+	Latitude        float64       `json:"latitude"`
+	Longitude       float64       `json:"longitude"`
+	Sunrise         time.Time     `json:"sunrise"`
+	Sunset          time.Time     `json:"sunset"`
+	ComputedSunrise time.Time     `json:"computed_sunrise"`
+	ComputedSunset  time.Time     `json:"computed_sunset"`
+	SunriseDiff     time.Duration `json:"sunrise_diff"` // processor - computed.
+	SunsetDiff      time.Duration `json:"sunset_diff"`  // processor - computed.
+}
+
+// checkSunTimes compares the processor's sunrise and sunset times to those
+// computed locally for the same location, an optional argument specifies
+// the allowed difference beyond which an error is returned.
+func (p *QSProcessor) checkSunTimes(ctx context.Context, lat, long float64, sess *streamconn.Session, args devices.OperationArgs) (any, error) {
+	tolerance := defaultSunTimesTolerance
+	if len(args.Args) > 0 {
+		var err error
+		if tolerance, err = time.ParseDuration(args.Args[0]); err != nil {
+			return nil, fmt.Errorf("failed to parse tolerance: %v", err)
+		}
+	}
+	rise, set, err := protocol.GetSunriseSunset(ctx, sess)
+	if err != nil {
+		return nil, err
+	}
+	st := astro.Sun(rise, lat, long)
+	check := SunTimesCheck{
+		Latitude:        lat,
+		Longitude:       long,
+		Sunrise:         rise,
+		Sunset:          set,
+		ComputedSunrise: st.Sunrise,
+		ComputedSunset:  st.Sunset,
+		SunriseDiff:     rise.Sub(st.Sunrise),
+		SunsetDiff:      set.Sub(st.Sunset),
+	}
+	fmt.Fprintf(args.Writer, "sunrise: %v, computed: %v, difference: %v\nsunset: %v, computed: %v, difference: %v\n",
+		check.Sunrise, check.ComputedSunrise, check.SunriseDiff, check.Sunset, check.ComputedSunset, check.SunsetDiff)
+	if st.Sunrise.IsZero() || st.Sunset.IsZero() {
+		return check, fmt.Errorf("the sun does not rise and set on %v at %v, %v", rise.Format(time.DateOnly), lat, long)
+	}
+	exceeds := func(d time.Duration) bool { return d > tolerance || d < -tolerance }
+	if exceeds(check.SunriseDiff) || exceeds(check.SunsetDiff) {
+		return check, fmt.Errorf("processor sunrise/sunset differ from computed values by %v/%v, more than %v", check.SunriseDiff, check.SunsetDiff, tolerance)
+	}
+	return check, nil
+}
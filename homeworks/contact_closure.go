@@ -6,13 +6,16 @@ package homeworks
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"log/slog"
-	"strconv"
 	"sync"
 	"time"
 
 	"cloudeng.io/logging/ctxlog"
 	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/lutron/protocol"
+	"gopkg.in/yaml.v3"
 )
 
 type ContactClosureOpenCloseConfig struct {
@@ -23,10 +26,59 @@ type ContactClosureOpenCloseConfig struct {
 	OperationInterval time.Duration `yaml:"operation_interval"`
 }
 
+const (
+	// Default pulse duration and interval between operations.
+	defaultPulseDuration     = 10 * time.Millisecond
+	defaultOperationInterval = 30 * time.Second
+	// Pulses longer than this are almost certainly configuration errors.
+	maxPulseDuration = 10 * time.Second
+)
+
 type ContactClosureOpenClose struct {
 	devices.DeviceBase[ContactClosureOpenCloseConfig]
 	processor *QSProcessor
-	mu        sync.Mutex
+	pulser    pulser
+}
+
+// pulser serializes the pulses sent to contact closure outputs and
+// enforces a minimum interval between successive operations.
+type pulser struct {
+	mu   sync.Mutex
+	next time.Time // the earliest time at which the next operation may start.
+}
+
+func validatePulse(cfg devices.DeviceConfigCommon, pulse, interval time.Duration) error {
+	if pulse < 0 || pulse > maxPulseDuration {
+		return configError(cfg, "pulse_duration", "must be in the range 0..%v, not %v", maxPulseDuration, pulse)
+	}
+	if interval < 0 {
+		return configError(cfg, "operation_interval", "must not be negative, not %v", interval)
+	}
+	return nil
+}
+
+func (cc *ContactClosureOpenClose) UnmarshalYAML(node *yaml.Node) error {
+	if err := node.Decode(&cc.DeviceConfigCustom); err != nil {
+		return err
+	}
+	cfg := &cc.DeviceConfigCustom
+	if err := validateID(cc.DeviceConfigCommon, "open_id", cfg.OpenID); err != nil {
+		return err
+	}
+	if err := validateID(cc.DeviceConfigCommon, "close_id", cfg.CloseID); err != nil {
+		return err
+	}
+	if cfg.OpenID == cfg.CloseID {
+		return configError(cc.DeviceConfigCommon, "close_id", "must differ from open_id: %v", cfg.OpenID)
+	}
+	return validatePulse(cc.DeviceConfigCommon, cfg.PulseDuration, cfg.OperationInterval)
+}
+
+func (cc *ContactClosureOpenClose) integrationIDs() []integrationID {
+	return []integrationID{
+		{"open_id", cc.DeviceConfigCustom.OpenID, protocol.OutputCommands},
+		{"close_id", cc.DeviceConfigCustom.CloseID, protocol.OutputCommands},
+	}
 }
 
 func (cc *ContactClosureOpenClose) Operations() map[string]devices.Operation {
@@ -51,55 +103,78 @@ func (cc *ContactClosureOpenClose) ControlledBy() devices.Controller {
 	return cc.processor
 }
 
-func (cc *ContactClosureOpenClose) pulse(ctx context.Context, id []byte, pulse, interval time.Duration) (any, error) {
-	cc.mu.Lock()
-	defer cc.mu.Unlock()
-	if cc.DeviceConfigCustom.PulseLow {
-		return cc.processor.contactClosurePulse(ctx, id, pulse, interval, '0', '1')
+// waitUntil waits until the specified time or for the context to be
+// canceled.
+func waitUntil(ctx context.Context, t time.Time) error {
+	d := time.Until(t)
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
-	return cc.processor.contactClosurePulse(ctx, id, pulse, interval, '1', '0')
 }
 
-func (cc *ContactClosureOpenClose) defaultIntervals() (time.Duration, time.Duration) {
-	// Default pulse duration is 10 milliseconds.
-	pulse := cc.DeviceConfigCustom.PulseDuration
+// pulse pulses the contact closure output with the specified ID, waiting
+// for the operation interval since the previous pulse to elapse first.
+func (pl *pulser) pulse(ctx context.Context, p *QSProcessor, device, op string, id int, pulse, interval time.Duration, pulseLow bool, w io.Writer) (PulseResult, error) {
+	grp := slog.Group("lutron",
+		"device", device,
+		"id", id,
+		"op", op,
+		"pulse", pulse.String(),
+		"interval", interval.String())
+	ctx = ctxlog.WithAttributes(ctx, grp)
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	// Wait for the operation interval to elapse without holding a
+	// session so that other devices are not blocked.
+	if err := waitUntil(ctx, pl.next); err != nil {
+		return PulseResult{ID: id, Requested: pulse}, err
+	}
+	defer func() {
+		pl.next = time.Now().Add(interval)
+	}()
+	l0, l1 := byte('1'), byte('0')
+	if pulseLow {
+		l0, l1 = l1, l0
+	}
+	res, err := p.contactClosurePulse(ctx, id, pulse, l0, l1)
+	if res.Actual > 0 {
+		fmt.Fprintf(w, "%v: pulse %v, actual %v\n", op, res.Requested, res.Actual)
+	}
+	return res, err
+}
+
+// pulseIntervals returns the configured pulse duration and operation
+// interval or their defaults if not configured.
+func pulseIntervals(pulse, interval, defaultInterval time.Duration) (time.Duration, time.Duration) {
 	if pulse == 0 {
-		cc.DeviceConfigCustom.PulseDuration = 10 * time.Millisecond
+		pulse = defaultPulseDuration
 	}
-	// Default operation interval is 30 seconds.
-	// If the operation interval is not set, we use a default of 30 seconds.
-	// This is to ensure that the device does not get polled too frequently.
-	interval := cc.DeviceConfigCustom.OperationInterval
+	// The operation interval ensures that the device is not operated
+	// too frequently.
 	if interval == 0 {
-		interval = time.Second * 30
+		interval = defaultInterval
 	}
 	return pulse, interval
 }
 
-func (cc *ContactClosureOpenClose) Open(ctx context.Context, _ devices.OperationArgs) (any, error) {
-	ids := strconv.Itoa(cc.DeviceConfigCustom.OpenID)
-	id := []byte(ids)
-	pulse, interval := cc.defaultIntervals()
-	grp := slog.Group("lutron",
-		"device", "contact-closure",
-		"id", ids,
-		"op", "open",
-		"pulse", pulse.String(),
-		"interval", interval.String())
-	ctx = ctxlog.WithAttributes(ctx, grp)
-	return cc.pulse(ctx, id, pulse, interval)
+func (cc *ContactClosureOpenClose) pulse(ctx context.Context, op string, id int, args devices.OperationArgs) (any, error) {
+	cfg := cc.DeviceConfigCustom
+	pulse, interval := pulseIntervals(cfg.PulseDuration, cfg.OperationInterval, defaultOperationInterval)
+	return cc.pulser.pulse(ctx, cc.processor, "contact-closure", op, id, pulse, interval, cfg.PulseLow, args.Writer)
 }
 
-func (cc *ContactClosureOpenClose) Close(ctx context.Context, _ devices.OperationArgs) (any, error) {
-	ids := strconv.Itoa(cc.DeviceConfigCustom.CloseID)
-	id := []byte(ids)
-	pulse, interval := cc.defaultIntervals()
-	grp := slog.Group("lutron",
-		"device", "contact-closure",
-		"id", ids,
-		"op", "close",
-		"pulse", pulse.String(),
-		"interval", interval.String())
-	ctx = ctxlog.WithAttributes(ctx, grp)
-	return cc.pulse(ctx, id, pulse, interval)
+func (cc *ContactClosureOpenClose) Open(ctx context.Context, args devices.OperationArgs) (any, error) {
+	return cc.pulse(ctx, "open", cc.DeviceConfigCustom.OpenID, args)
+}
+
+func (cc *ContactClosureOpenClose) Close(ctx context.Context, args devices.OperationArgs) (any, error) {
+	return cc.pulse(ctx, "close", cc.DeviceConfigCustom.CloseID, args)
 }
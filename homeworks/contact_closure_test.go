@@ -0,0 +1,133 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package homeworks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/lutron/internal/testutil"
+	"github.com/cosnicolaou/lutron/protocol"
+)
+
+func newContactClosureTransport() *testutil.MockTransport {
+	mock := testutil.NewMockTransport(testing.Verbose())
+	for _, id := range []string{"5", "6"} {
+		mock.SetResponse("#OUTPUT,"+id+",1,1\r\n", "QNET> ")
+		mock.SetResponse("#OUTPUT,"+id+",1,0\r\n", "QNET> ")
+	}
+	return mock
+}
+
+func TestContactClosurePulse(t *testing.T) {
+	cc := &ContactClosureOpenClose{}
+	cc.DeviceConfigCustom = ContactClosureOpenCloseConfig{
+		OpenID:            5,
+		CloseID:           6,
+		PulseDuration:     20 * time.Millisecond,
+		OperationInterval: 50 * time.Millisecond,
+	}
+	ctx, sent := newMockDevice(t, newContactClosureTransport(), cc)
+	out := &bytes.Buffer{}
+	res, err := cc.Open(ctx, devices.OperationArgs{Writer: out})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pr := res.(PulseResult)
+	if pr.ID != 5 || pr.Requested != 20*time.Millisecond || pr.Actual < pr.Requested || pr.Canceled {
+		t.Errorf("unexpected result: %+v", pr)
+	}
+	if out.Len() == 0 {
+		t.Errorf("no output")
+	}
+
+	// The operation interval is honoured.
+	start := time.Now()
+	if _, err := cc.Close(ctx, devices.OperationArgs{Writer: out}); err != nil {
+		t.Fatal(err)
+	}
+	if took := time.Since(start); took < 50*time.Millisecond {
+		t.Errorf("operation interval not honoured: %v", took)
+	}
+
+	// Waiting for the operation interval is cancellable.
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := cc.Open(cctx, devices.OperationArgs{Writer: out}); !errors.Is(err, context.Canceled) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if got, want := sent(), []string{
+		"admin\r\n",
+		"#OUTPUT,5,1,1\r\n", "#OUTPUT,5,1,0\r\n",
+		"#OUTPUT,6,1,1\r\n", "#OUTPUT,6,1,0\r\n",
+	}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestContactClosurePulseCanceled(t *testing.T) {
+	cc := &ContactClosureOpenClose{}
+	cc.DeviceConfigCustom = ContactClosureOpenCloseConfig{
+		OpenID:        5,
+		CloseID:       6,
+		PulseLow:      true,
+		PulseDuration: 5 * time.Second,
+	}
+	ctx, sent := newMockDevice(t, newContactClosureTransport(), cc)
+	// The release must be sent, without delay, even when rate limited.
+	cc.processor.limiter = protocol.NewRateLimiter(protocol.RateLimit{MinGap: time.Hour})
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	res, err := cc.Close(ctx, devices.OperationArgs{Writer: &bytes.Buffer{}})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if took := time.Since(start); took > time.Second {
+		t.Errorf("cancellation not honoured: %v", took)
+	}
+	pr := res.(PulseResult)
+	if !pr.Canceled || pr.Actual >= pr.Requested {
+		t.Errorf("unexpected result: %+v", pr)
+	}
+	if got, want := sent(), []string{
+		"admin\r\n",
+		"#OUTPUT,6,1,0\r\n", "#OUTPUT,6,1,1\r\n",
+	}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestContactClosureCanceledDuringStart(t *testing.T) {
+	cc := &ContactClosureOpenClose{}
+	cc.DeviceConfigCustom = ContactClosureOpenCloseConfig{
+		OpenID:        5,
+		CloseID:       6,
+		PulseDuration: 5 * time.Second,
+	}
+	ctx, sent := newMockDevice(t, newContactClosureTransport(), cc)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	cancelOnSend(cc.processor, "#OUTPUT,5,1,1\r\n", cancel)
+	res, err := cc.Open(ctx, devices.OperationArgs{Writer: &bytes.Buffer{}})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if pr := res.(PulseResult); !pr.Canceled || pr.Actual >= pr.Requested {
+		t.Errorf("unexpected result: %+v", pr)
+	}
+	// The pulse is always ended once it has been started.
+	if got, want := sent(), []string{
+		"admin\r\n",
+		"#OUTPUT,5,1,1\r\n", "#OUTPUT,5,1,0\r\n",
+	}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
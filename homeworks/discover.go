@@ -0,0 +1,111 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package homeworks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"cloudeng.io/logging/ctxlog"
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/lutron/protocol"
+)
+
+// Default range of integration IDs scanned by the discover operation.
+const (
+	defaultDiscoverFrom = 1
+	defaultDiscoverTo   = 255
+)
+
+// Inventory represents the objects found by scanning a range of
+// integration IDs.
+type Inventory struct {
+	Outputs     []protocol.IntegrationIDDetails `json:"outputs,omitempty"`
+	Devices     []protocol.IntegrationIDDetails `json:"devices,omitempty"`
+	ShadeGroups []protocol.IntegrationIDDetails `json:"shade_groups,omitempty"`
+	Other       []protocol.IntegrationIDDetails `json:"other,omitempty"`
+}
+
+func (inv *Inventory) add(d protocol.IntegrationIDDetails) {
+	switch d.Type {
+	case protocol.IntegrationIDOutput:
+		inv.Outputs = append(inv.Outputs, d)
+	case protocol.IntegrationIDDevice:
+		inv.Devices = append(inv.Devices, d)
+	case protocol.IntegrationIDShadeGroup:
+		inv.ShadeGroups = append(inv.ShadeGroups, d)
+	default:
+		inv.Other = append(inv.Other, d)
+	}
+}
+
+func (p *QSProcessor) discoverID(ctx context.Context, id int) (protocol.IntegrationIDDetails, error) {
+	ctx, sess, err := p.session(ctx)
+	if err != nil {
+		return protocol.IntegrationIDDetails{}, err
+	}
+	defer sess.Release()
+	return protocol.Discover(ctx, sess, id)
+}
+
+// Discover scans the integration IDs in the range [from, to] and returns
+// an inventory of the outputs, devices and shade groups found. A new
+// session is used for each ID so that other operations are not blocked
+// for the duration of the scan.
+func (p *QSProcessor) Discover(ctx context.Context, from, to int) (Inventory, error) {
+	var inv Inventory
+	if from <= 0 || to < from {
+		return inv, fmt.Errorf("invalid integration id range: %v..%v", from, to)
+	}
+	for id := from; id <= to; id++ {
+		if err := ctx.Err(); err != nil {
+			return inv, err
+		}
+		details, err := p.discoverID(ctx, id)
+		if err != nil {
+			if errors.Is(err, protocol.ErrAccessPointObjectDoesNotExist) {
+				continue
+			}
+			return inv, err
+		}
+		ctxlog.Info(ctx, "discover", "id", id, "type", details.Type, "serial", details.Serial)
+		inv.add(details)
+	}
+	return inv, nil
+}
+
+func (p *QSProcessor) discover(ctx context.Context, args devices.OperationArgs) (any, error) {
+	from, to := defaultDiscoverFrom, defaultDiscoverTo
+	switch len(args.Args) {
+	case 0:
+	case 2:
+		var err error
+		if from, err = strconv.Atoi(args.Args[0]); err != nil {
+			return nil, fmt.Errorf("invalid integration id: %q", args.Args[0])
+		}
+		if to, err = strconv.Atoi(args.Args[1]); err != nil {
+			return nil, fmt.Errorf("invalid integration id: %q", args.Args[1])
+		}
+	default:
+		return nil, fmt.Errorf("must specify both the first and last integration id to scan")
+	}
+	inv, err := p.Discover(ctx, from, to)
+	for _, grp := range []struct {
+		name    string
+		details []protocol.IntegrationIDDetails
+	}{
+		{"output", inv.Outputs},
+		{"device", inv.Devices},
+		{"shade group", inv.ShadeGroups},
+		{"other", inv.Other},
+	} {
+		for _, d := range grp.details {
+			fmt.Fprintf(args.Writer, "%v: %v %v %v\n", d.ID, grp.name, d.Type, d.Serial)
+		}
+	}
+	return inv, err
+}
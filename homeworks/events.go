@@ -0,0 +1,119 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package homeworks
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/cosnicolaou/lutron/protocol"
+)
+
+// EventHub distributes monitoring events to any number of subscribers.
+// Publishing never blocks, events are dropped for subscribers that are
+// not keeping up and the number dropped is recorded for the subscriber.
+type EventHub struct {
+	mu   sync.Mutex
+	subs map[*Subscription]struct{}
+}
+
+// Subscription represents a subscription to an EventHub.
+type Subscription struct {
+	ch      chan protocol.Event
+	dropped atomic.Int64
+}
+
+// Events returns the channel on which events are delivered, it is
+// closed when the context passed to Subscribe is canceled.
+func (s *Subscription) Events() <-chan protocol.Event {
+	return s.ch
+}
+
+// Dropped returns the number of events dropped since the last call
+// to Dropped.
+func (s *Subscription) Dropped() int64 {
+	return s.dropped.Swap(0)
+}
+
+// NewEventHub returns a new EventHub.
+func NewEventHub() *EventHub {
+	return &EventHub{subs: map[*Subscription]struct{}{}}
+}
+
+// Subscribe returns a new Subscription whose channel is buffered with
+// the specified size. The subscription is removed when the context
+// is canceled.
+func (h *EventHub) Subscribe(ctx context.Context, size int) *Subscription {
+	sub := &Subscription{ch: make(chan protocol.Event, size)}
+	h.mu.Lock()
+	h.subs[sub] = struct{}{}
+	h.mu.Unlock()
+	go func() {
+		<-ctx.Done()
+		h.mu.Lock()
+		delete(h.subs, sub)
+		close(sub.ch)
+		h.mu.Unlock()
+	}()
+	return sub
+}
+
+// Len returns the number of current subscribers.
+func (h *EventHub) Len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs)
+}
+
+// Publish delivers the event to all current subscribers.
+func (h *EventHub) Publish(ev protocol.Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subs {
+		select {
+		case sub.ch <- ev:
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+}
+
+// notifier signals any number of subscribers that something, such as
+// a new connection to the processor, has happened. Notifications are
+// coalesced for subscribers that have not yet received the previous one.
+type notifier struct {
+	mu   sync.Mutex
+	subs map[chan struct{}]struct{}
+}
+
+func (n *notifier) subscribe(ctx context.Context) <-chan struct{} {
+	ch := make(chan struct{}, 1)
+	n.mu.Lock()
+	if n.subs == nil {
+		n.subs = map[chan struct{}]struct{}{}
+	}
+	n.subs[ch] = struct{}{}
+	n.mu.Unlock()
+	go func() {
+		<-ctx.Done()
+		n.mu.Lock()
+		delete(n.subs, ch)
+		close(ch)
+		n.mu.Unlock()
+	}()
+	return ch
+}
+
+func (n *notifier) notify() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for ch := range n.subs {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
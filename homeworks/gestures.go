@@ -0,0 +1,286 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package homeworks
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cosnicolaou/lutron/protocol"
+)
+
+// GestureType represents a button gesture.
+type GestureType string
+
+const (
+	SingleTap GestureType = "single-tap"
+	DoubleTap GestureType = "double-tap"
+	TripleTap GestureType = "triple-tap"
+	LongPress GestureType = "long-press"
+)
+
+var tapGestures = []GestureType{SingleTap, DoubleTap, TripleTap}
+
+// GestureTiming represents the timing windows used to recognise gestures.
+type GestureTiming struct {
+	// TapWindow is the time allowed between releasing a button and
+	// pressing it again for the presses to be treated as a multi-tap,
+	// it defaults to 400ms.
+	TapWindow time.Duration `yaml:"tap_window"`
+	// LongPress is the time for which a button must be held down to be
+	// treated as a long press, it defaults to 1s. A hold action reported
+	// by the processor is always treated as a long press.
+	LongPress time.Duration `yaml:"long_press"`
+}
+
+const (
+	defaultTapWindow     = 400 * time.Millisecond
+	defaultLongPress     = time.Second
+	defaultGestureEvents = 64
+	maxTaps              = 3
+)
+
+// Gesture represents a recognised gesture for a keypad button, Device
+// and Button are the names of the keypad device and button from the
+// configuration if they are known.
+type Gesture struct {
+	Time      time.Time   `json:"time"`
+	Type      GestureType `json:"type"`
+	ID        int         `json:"id"`
+	Component int         `json:"component"`
+	Device    string      `json:"device,omitempty"`
+	Button    string      `json:"button,omitempty"`
+}
+
+type buttonKey struct {
+	id, component int
+}
+
+type buttonName struct {
+	device, button string
+}
+
+// buttonState tracks the progress of a gesture for a single button.
+type buttonState struct {
+	taps     int       // number of presses seen so far.
+	pressed  bool      // the button is currently down.
+	long     bool      // a long press has been reported for the current press.
+	deadline time.Time // when the long press or tap window expires.
+}
+
+// GestureRecognizer turns the button press, release and hold events
+// received from the processor's monitoring stream into single, double
+// and triple taps and long presses.
+type GestureRecognizer struct {
+	processor *QSProcessor
+	timing    GestureTiming
+
+	mu      sync.Mutex
+	names   map[buttonKey]buttonName
+	buttons map[buttonKey]*buttonState
+	subs    map[chan Gesture]struct{}
+}
+
+// keypadButtons is implemented by devices that name keypad buttons.
+type keypadButtons interface {
+	keypadButtons() (int, []Button)
+}
+
+// NewGestureRecognizer returns a new GestureRecognizer for the processor,
+// device and button names are obtained from all keypad devices configured
+// for the processor.
+func NewGestureRecognizer(p *QSProcessor, timing GestureTiming) *GestureRecognizer {
+	if timing.TapWindow <= 0 {
+		timing.TapWindow = defaultTapWindow
+	}
+	if timing.LongPress <= 0 {
+		timing.LongPress = defaultLongPress
+	}
+	g := &GestureRecognizer{
+		processor: p,
+		timing:    timing,
+		names:     map[buttonKey]buttonName{},
+		buttons:   map[buttonKey]*buttonState{},
+		subs:      map[chan Gesture]struct{}{},
+	}
+	for name, dev := range p.System().Devices {
+		if dev.ControlledBy() != p {
+			continue
+		}
+		kb, ok := dev.(keypadButtons)
+		if !ok {
+			continue
+		}
+		id, buttons := kb.keypadButtons()
+		for _, b := range buttons {
+			g.names[buttonKey{id, b.Component}] = buttonName{device: name, button: b.Name}
+		}
+	}
+	return g
+}
+
+// Subscribe returns a channel on which gestures are delivered. The
+// channel is buffered with the specified size and gestures are dropped
+// if it is full. The channel is closed when the context is canceled.
+func (g *GestureRecognizer) Subscribe(ctx context.Context, size int) <-chan Gesture {
+	ch := make(chan Gesture, size)
+	g.mu.Lock()
+	g.subs[ch] = struct{}{}
+	g.mu.Unlock()
+	go func() {
+		<-ctx.Done()
+		g.mu.Lock()
+		delete(g.subs, ch)
+		close(ch)
+		g.mu.Unlock()
+	}()
+	return ch
+}
+
+func (g *GestureRecognizer) publish(gestures []Gesture) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, gs := range gestures {
+		for ch := range g.subs {
+			select {
+			case ch <- gs:
+			default:
+			}
+		}
+	}
+}
+
+func (g *GestureRecognizer) gesture(key buttonKey, typ GestureType, when time.Time) Gesture {
+	n := g.names[key]
+	return Gesture{
+		Time:      when,
+		Type:      typ,
+		ID:        key.id,
+		Component: key.component,
+		Device:    n.device,
+		Button:    n.button,
+	}
+}
+
+func tapGesture(taps int) GestureType {
+	return tapGestures[min(taps, maxTaps)-1]
+}
+
+// handle updates the state of the button referred to by the event and
+// returns any gestures that are complete as a result.
+func (g *GestureRecognizer) handle(ev protocol.Event, now time.Time) []Gesture {
+	if ev.Type() != protocol.EventButton || len(ev.Fields) < 2 {
+		return nil
+	}
+	component, err := strconv.Atoi(ev.Fields[0])
+	if err != nil {
+		return nil
+	}
+	key := buttonKey{ev.ID, component}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	bs := g.buttons[key]
+	if bs == nil {
+		// Gestures always start with a press, releases and holds
+		// for presses that were not seen are ignored.
+		if ev.Action() != protocol.DeviceActionPress {
+			return nil
+		}
+		bs = &buttonState{}
+		g.buttons[key] = bs
+	}
+	switch ev.Action() {
+	case protocol.DeviceActionPress:
+		bs.taps++
+		bs.pressed = true
+		bs.long = false
+		bs.deadline = now.Add(g.timing.LongPress)
+	case protocol.DeviceActionHold:
+		if bs.pressed && !bs.long {
+			bs.long = true
+			return []Gesture{g.gesture(key, LongPress, now)}
+		}
+	case protocol.DeviceActionRelease:
+		if !bs.pressed {
+			return nil
+		}
+		if bs.long {
+			delete(g.buttons, key)
+			return nil
+		}
+		if bs.taps >= maxTaps {
+			delete(g.buttons, key)
+			return []Gesture{g.gesture(key, tapGesture(bs.taps), now)}
+		}
+		bs.pressed = false
+		bs.deadline = now.Add(g.timing.TapWindow)
+	}
+	return nil
+}
+
+// expire returns the gestures whose timing windows have expired by now.
+func (g *GestureRecognizer) expire(now time.Time) []Gesture {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var gestures []Gesture
+	for key, bs := range g.buttons {
+		if bs.long || now.Before(bs.deadline) {
+			continue
+		}
+		if bs.pressed {
+			bs.long = true
+			gestures = append(gestures, g.gesture(key, LongPress, bs.deadline))
+			continue
+		}
+		delete(g.buttons, key)
+		gestures = append(gestures, g.gesture(key, tapGesture(bs.taps), bs.deadline))
+	}
+	return gestures
+}
+
+// nextDeadline returns the earliest time at which a timing window expires
+// and false if there are no pending windows.
+func (g *GestureRecognizer) nextDeadline() (time.Time, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var next time.Time
+	for _, bs := range g.buttons {
+		if bs.long {
+			continue
+		}
+		if next.IsZero() || bs.deadline.Before(next) {
+			next = bs.deadline
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// Run recognises gestures from the processor's monitoring events until
+// the context is canceled. Button monitoring must be enabled and
+// QSProcessor.Monitor must be running.
+func (g *GestureRecognizer) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	sub := g.processor.Events().Subscribe(ctx, defaultGestureEvents)
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	defer timer.Stop()
+	for {
+		if next, ok := g.nextDeadline(); ok {
+			timer.Reset(time.Until(next))
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case ev := <-sub.Events():
+			g.publish(g.handle(ev, time.Now()))
+		case <-timer.C:
+			g.publish(g.expire(time.Now()))
+		}
+		timer.Stop()
+	}
+}
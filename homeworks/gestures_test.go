@@ -0,0 +1,89 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package homeworks
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/lutron/internal/testutil"
+)
+
+func TestGestureRecognition(t *testing.T) {
+	_, p := newMockProcessor(t, testutil.NewMockTransport(testing.Verbose()))
+	g := NewGestureRecognizer(p, GestureTiming{})
+	start := time.Now()
+	at := func(ms int) time.Time {
+		return start.Add(time.Duration(ms) * time.Millisecond)
+	}
+	type step struct {
+		event string // an event, or empty to expire timing windows.
+		ms    int
+		want  GestureType
+	}
+	for i, tc := range [][]step{
+		{{"~DEVICE,10,3,3", 0, ""}, {"~DEVICE,10,3,4", 100, ""}, {"", 499, ""}, {"", 500, SingleTap}},
+		{{"~DEVICE,10,3,3", 0, ""}, {"~DEVICE,10,3,4", 100, ""}, {"~DEVICE,10,3,3", 400, ""}, {"~DEVICE,10,3,4", 450, ""}, {"", 850, DoubleTap}},
+		{{"~DEVICE,10,3,3", 0, ""}, {"~DEVICE,10,3,4", 100, ""}, {"~DEVICE,10,3,3", 200, ""}, {"~DEVICE,10,3,4", 300, ""}, {"~DEVICE,10,3,3", 400, ""}, {"~DEVICE,10,3,4", 500, TripleTap}},
+		// Long press detected by timing and by a hold action.
+		{{"~DEVICE,10,3,3", 0, ""}, {"", 999, ""}, {"", 1000, LongPress}, {"", 5000, ""}, {"~DEVICE,10,3,4", 5000, ""}, {"", 10000, ""}},
+		{{"~DEVICE,10,3,3", 0, ""}, {"~DEVICE,10,3,5", 500, LongPress}, {"~DEVICE,10,3,4", 700, ""}, {"", 10000, ""}},
+		// Releases and holds without a press, and other events, are ignored.
+		{{"~DEVICE,10,3,4", 0, ""}, {"~DEVICE,10,3,5", 0, ""}, {"~OUTPUT,10,1,50", 0, ""}, {"", 10000, ""}},
+	} {
+		for j, s := range tc {
+			var got []Gesture
+			if len(s.event) == 0 {
+				got = g.expire(at(s.ms))
+			} else {
+				got = g.handle(mustParseEvent(t, s.event), at(s.ms))
+			}
+			if len(s.want) == 0 {
+				if len(got) != 0 {
+					t.Errorf("%v:%v: unexpected gestures: %v", i, j, got)
+				}
+				continue
+			}
+			if len(got) != 1 || got[0].Type != s.want || got[0].ID != 10 || got[0].Component != 3 {
+				t.Errorf("%v:%v: got %v, want %v", i, j, got, s.want)
+			}
+		}
+		if _, ok := g.nextDeadline(); ok {
+			t.Errorf("%v: unexpected pending gesture", i)
+		}
+	}
+}
+
+func TestGestureRecognizer(t *testing.T) {
+	ctx, p := newMockProcessor(t, testutil.NewMockTransport(testing.Verbose()))
+	kp := &Keypad{processor: p}
+	kp.DeviceConfigCustom = KeypadConfig{ID: 10, Buttons: map[string]int{"lights": 3}}
+	p.SetSystem(devices.System{Devices: map[string]devices.Device{"hall": kp}})
+
+	g := NewGestureRecognizer(p, GestureTiming{TapWindow: 20 * time.Millisecond})
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	ch := g.Subscribe(ctx, 10)
+	go g.Run(ctx) //nolint:errcheck
+	for p.Events().Len() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	for _, line := range []string{"~DEVICE,10,3,3", "~DEVICE,10,3,4", "~DEVICE,10,4,3", "~DEVICE,10,4,4"} {
+		p.Events().Publish(mustParseEvent(t, line))
+	}
+	got := map[int]Gesture{}
+	for range 2 {
+		gs := <-ch
+		got[gs.Component] = gs
+	}
+	if gs := got[3]; gs.Type != SingleTap || gs.Device != "hall" || gs.Button != "lights" {
+		t.Errorf("unexpected gesture: %+v", gs)
+	}
+	if gs := got[4]; gs.Type != SingleTap || gs.Device != "" || gs.Button != "" {
+		t.Errorf("unexpected gesture: %+v", gs)
+	}
+}
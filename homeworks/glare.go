@@ -0,0 +1,271 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package homeworks
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"cloudeng.io/logging/ctxlog"
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/lutron/astro"
+)
+
+// GlareConfig represents the geometry of the window covered by a shade
+// and is used to compute the level at which the shade keeps direct sun
+// within a given depth into the room. All lengths must use the same
+// units, eg. metres or feet.
+type GlareConfig struct {
+	// Azimuth is the direction that the window faces, in degrees
+	// clockwise from true north.
+	Azimuth float64 `yaml:"azimuth"`
+	// WindowBottom and WindowTop are the heights of the bottom and top
+	// of the window above the floor.
+	WindowBottom float64 `yaml:"window_bottom"`
+	WindowTop    float64 `yaml:"window_top"`
+	// Overhang is the depth of any overhang, or recess, at the top
+	// of the window.
+	Overhang float64 `yaml:"overhang"`
+	// Depth is the maximum distance into the room that direct sun
+	// may reach.
+	Depth float64 `yaml:"depth"`
+	// Hysteresis is the minimum change in level, in percent, for which
+	// the shade will be moved, it defaults to 10. Moves to fully open
+	// or fully closed are always made.
+	Hysteresis float64 `yaml:"hysteresis"`
+	// MinInterval is the minimum time between adjustments, it defaults
+	// to 15 minutes.
+	MinInterval time.Duration `yaml:"min_interval"`
+}
+
+const (
+	defaultGlareHysteresis  = 10.0
+	defaultGlareMinInterval = 15 * time.Minute
+	defaultGlareInterval    = 5 * time.Minute
+)
+
+func (g GlareConfig) validate(cfg devices.DeviceConfigCommon) error {
+	if g.Azimuth < 0 || g.Azimuth >= 360 {
+		return configError(cfg, "glare.azimuth", "must be in the range 0..360, not %v", g.Azimuth)
+	}
+	if g.WindowBottom < 0 || g.WindowTop <= g.WindowBottom {
+		return configError(cfg, "glare.window_top", "must be greater than window_bottom (%v), not %v", g.WindowBottom, g.WindowTop)
+	}
+	if g.Overhang < 0 {
+		return configError(cfg, "glare.overhang", "must not be negative, not %v", g.Overhang)
+	}
+	if g.Depth < 0 {
+		return configError(cfg, "glare.depth", "must not be negative, not %v", g.Depth)
+	}
+	if g.Hysteresis < 0 || g.Hysteresis > 100 {
+		return configError(cfg, "glare.hysteresis", "must be in the range 0..100, not %v", g.Hysteresis)
+	}
+	if g.MinInterval < 0 {
+		return configError(cfg, "glare.min_interval", "must not be negative, not %v", g.MinInterval)
+	}
+	return nil
+}
+
+func (g GlareConfig) hysteresis() float64 {
+	if g.Hysteresis > 0 {
+		return g.Hysteresis
+	}
+	return defaultGlareHysteresis
+}
+
+func (g GlareConfig) minInterval() time.Duration {
+	if g.MinInterval > 0 {
+		return g.MinInterval
+	}
+	return defaultGlareMinInterval
+}
+
+// Level returns the shade level, 0 being fully closed and 100 fully
+// open, that prevents direct sun from reaching further than Depth into
+// the room for the specified position of the sun. The shade is assumed to
+// lower from the top of the window.
+func (g GlareConfig) Level(sun astro.Position) float64 {
+	offset := math.Mod(sun.Azimuth-g.Azimuth+540, 360) - 180
+	if sun.Elevation <= 0 || math.Abs(offset) >= 90 {
+		return 100 // the sun is not shining on the window.
+	}
+	// The profile angle is the elevation of the sun projected onto the
+	// vertical plane perpendicular to the window.
+	tanProfile := math.Tan(sun.Elevation*math.Pi/180) / math.Cos(offset*math.Pi/180)
+	// Sun entering the window above this height reaches beyond Depth.
+	highest := g.Depth * tanProfile
+	// Sun entering the window above this height is blocked by the overhang.
+	shaded := g.WindowTop - g.Overhang*tanProfile
+	if highest >= shaded {
+		return 100
+	}
+	if highest <= g.WindowBottom {
+		return 0
+	}
+	return math.Round((highest - g.WindowBottom) / (g.WindowTop - g.WindowBottom) * 100)
+}
+
+// GlareResult represents the outcome of a glare adjustment.
+type GlareResult struct {
+	Sun      astro.Position `json:"sun"`
+	Level    float64        `json:"level"`
+	Previous float64        `json:"previous"`
+	Moved    bool           `json:"moved"`
+}
+
+// glareState records the last level set by glare control so that small
+// changes and frequent adjustments can be suppressed.
+type glareState struct {
+	mu    sync.Mutex
+	valid bool
+	level float64
+	when  time.Time
+}
+
+func newGlareState() *glareState {
+	return &glareState{}
+}
+
+// update returns the previously set level and true, recording the new
+// level, if the shade should be moved to it. A nil glareState always
+// moves the shade.
+func (gs *glareState) update(g GlareConfig, level float64, now time.Time, force bool) (float64, bool) {
+	if gs == nil {
+		return level, true
+	}
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	prev := gs.level
+	move := force || !gs.valid
+	if !move && level != prev && now.Sub(gs.when) >= g.minInterval() {
+		move = level == 0 || level == 100 || math.Abs(level-prev) >= g.hysteresis()
+	}
+	if move {
+		gs.valid, gs.level, gs.when = true, level, now
+	}
+	return prev, move
+}
+
+func (gs *glareState) reset() {
+	if gs == nil {
+		return
+	}
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.valid = false
+}
+
+// sunPosition returns the position of the sun at the specified time using
+// the location configured for the system or reported by the processor.
+func (p *QSProcessor) sunPosition(ctx context.Context, when time.Time) (astro.Position, error) {
+	lat, long, err := p.location(ctx)
+	if err != nil {
+		return astro.Position{}, err
+	}
+	return astro.SunPosition(when, lat, long), nil
+}
+
+// adjustGlare moves the shade, using the supplied set operation, to the
+// level computed for the position of the sun at the specified time,
+// subject to the configured hysteresis and minimum interval unless
+// 'force' is supplied as an argument. Any remaining arguments, such as
+// 'wait', are passed on to the set operation.
+func (sb hwShadeBase) adjustGlare(ctx context.Context, now time.Time, args devices.OperationArgs, set devices.Operation) (GlareResult, error) {
+	g := sb.DeviceConfigCustom.Glare
+	if g == nil {
+		return GlareResult{}, fmt.Errorf("glare control is not configured")
+	}
+	force := slices.Contains(args.Args, "force")
+	sun, err := sb.processor.sunPosition(ctx, now)
+	if err != nil {
+		return GlareResult{}, err
+	}
+	result := GlareResult{Sun: sun, Level: g.Level(sun)}
+	result.Previous, result.Moved = sb.glare.update(*g, result.Level, now, force)
+	if !result.Moved {
+		fmt.Fprintf(args.Writer, "glare: sun at %.1f/%.1f, level %v, unchanged from %v\n", sun.Azimuth, sun.Elevation, result.Level, result.Previous)
+		return result, nil
+	}
+	fmt.Fprintf(args.Writer, "glare: sun at %.1f/%.1f, setting level to %v\n", sun.Azimuth, sun.Elevation, result.Level)
+	sargs := args
+	sargs.Args = []string{strconv.Itoa(int(result.Level))}
+	for _, a := range args.Args {
+		if a != "force" {
+			sargs.Args = append(sargs.Args, a)
+		}
+	}
+	if _, err := set(ctx, sargs); err != nil {
+		// Ensure that the next adjustment is not suppressed.
+		sb.glare.reset()
+		return result, err
+	}
+	return result, nil
+}
+
+// glareShade is implemented by shades that support glare control.
+type glareShade interface {
+	glareEnabled() bool
+	adjustGlare(ctx context.Context, now time.Time, args devices.OperationArgs) (GlareResult, error)
+}
+
+// GlareController periodically adjusts all of the shades configured for
+// glare control that are controlled by a processor.
+type GlareController struct {
+	processor *QSProcessor
+	interval  time.Duration
+}
+
+// NewGlareController returns a new GlareController that adjusts shades at
+// the specified interval, which defaults to 5 minutes. The hysteresis and
+// minimum interval configured for each shade limit how often it moves.
+func NewGlareController(p *QSProcessor, interval time.Duration) *GlareController {
+	if interval <= 0 {
+		interval = defaultGlareInterval
+	}
+	return &GlareController{processor: p, interval: interval}
+}
+
+// Adjust adjusts all of the shades configured for glare control for the
+// position of the sun at the specified time.
+func (gc *GlareController) Adjust(ctx context.Context, now time.Time) {
+	for name, dev := range gc.processor.System().Devices {
+		if dev.ControlledBy() != gc.processor {
+			continue
+		}
+		gs, ok := dev.(glareShade)
+		if !ok || !gs.glareEnabled() {
+			continue
+		}
+		res, err := gs.adjustGlare(ctx, now, devices.OperationArgs{Writer: io.Discard})
+		if err != nil {
+			ctxlog.Info(ctx, "glare: failed to adjust shade", "device", name, "err", err)
+			continue
+		}
+		if res.Moved {
+			ctxlog.Info(ctx, "glare: adjusted shade", "device", name, "azimuth", res.Sun.Azimuth, "elevation", res.Sun.Elevation, "level", res.Level)
+		}
+	}
+}
+
+// Run adjusts the shades immediately and then at the configured interval
+// until the context is canceled.
+func (gc *GlareController) Run(ctx context.Context) error {
+	ticker := time.NewTicker(gc.interval)
+	defer ticker.Stop()
+	for {
+		gc.Adjust(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package homeworks
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/lutron/astro"
+	"github.com/cosnicolaou/lutron/internal/testutil"
+)
+
+func TestGlareLevel(t *testing.T) {
+	// A south facing window from 1 to 2.5 above the floor.
+	g := GlareConfig{Azimuth: 180, WindowBottom: 1, WindowTop: 2.5, Depth: 1}
+	elevation := func(tanProfile float64) float64 {
+		return math.Atan(tanProfile) * 180 / math.Pi
+	}
+	for i, tc := range []struct {
+		g     GlareConfig
+		sun   astro.Position
+		level float64
+	}{
+		{g, astro.Position{Azimuth: 180, Elevation: -5}, 100}, // below the horizon.
+		{g, astro.Position{Azimuth: 0, Elevation: 45}, 100},   // behind the window.
+		{g, astro.Position{Azimuth: 270, Elevation: 45}, 100}, // parallel to the window.
+		{g, astro.Position{Azimuth: 180, Elevation: 80}, 100}, // high in the sky.
+		{g, astro.Position{Azimuth: 180, Elevation: 20}, 0},   // low in the sky.
+		{g, astro.Position{Azimuth: 180, Elevation: 45}, 0},   // reaches exactly to the depth.
+		{g, astro.Position{Azimuth: 180, Elevation: elevation(1.75)}, 50},
+		// The profile angle is higher when the sun is not directly in
+		// front of the window: tan(profile) = tan(elevation)/cos(60).
+		{g, astro.Position{Azimuth: 120, Elevation: elevation(0.875)}, 50},
+		{g, astro.Position{Azimuth: 240, Elevation: elevation(0.875)}, 50},
+		// The overhang blocks the sun at the top of the window.
+		{GlareConfig{Azimuth: 180, WindowBottom: 1, WindowTop: 2.5, Depth: 1, Overhang: 0.5}, astro.Position{Azimuth: 180, Elevation: elevation(1.75)}, 100},
+		{GlareConfig{Azimuth: 180, WindowBottom: 1, WindowTop: 2.5, Depth: 1, Overhang: 0.2}, astro.Position{Azimuth: 180, Elevation: elevation(1.75)}, 50},
+		// Wrap around north.
+		{GlareConfig{Azimuth: 350, WindowBottom: 1, WindowTop: 2.5, Depth: 1}, astro.Position{Azimuth: 10, Elevation: elevation(1.75 * math.Cos(20*math.Pi/180))}, 50},
+	} {
+		if got, want := tc.g.Level(tc.sun), tc.level; got != want {
+			t.Errorf("%v: %+v: got %v, want %v", i, tc.sun, got, want)
+		}
+	}
+}
+
+func TestGlareHysteresis(t *testing.T) {
+	g := GlareConfig{Hysteresis: 10, MinInterval: time.Minute}
+	gs := newGlareState()
+	now := time.Now()
+	for i, tc := range []struct {
+		level float64
+		after time.Duration
+		force bool
+		move  bool
+	}{
+		{50, 0, false, true},                 // first adjustment.
+		{70, 30 * time.Second, false, false}, // too soon.
+		{55, time.Minute, false, false},      // too small.
+		{60, time.Minute, false, true},       // large enough.
+		{65, time.Minute, true, true},        // forced.
+		{100, 30 * time.Second, false, false},
+		{100, time.Minute, false, true},  // fully open.
+		{100, time.Minute, false, false}, // unchanged.
+		{95, time.Minute, false, false},
+		{0, time.Minute, false, true}, // fully closed.
+	} {
+		now = now.Add(tc.after)
+		if _, got := gs.update(g, tc.level, now, tc.force); got != tc.move {
+			t.Errorf("%v: %v: got %v, want %v", i, tc.level, got, tc.move)
+		}
+		if !tc.move {
+			// Suppressed adjustments do not restart the interval.
+			now = now.Add(-tc.after)
+		}
+	}
+}
+
+func TestGlareAdjust(t *testing.T) {
+	mock := testutil.NewMockTransport(testing.Verbose())
+	mock.SetResponse("?SYSTEM,4\r\n", "~SYSTEM,4,37.42,-122.08\r\nQNET> ")
+	ctx, p := newMockProcessor(t, mock)
+	sent := recordSent(t, p)
+	s := &HWShade{hwShadeBase: hwShadeBase{processor: p, travel: newTravelEstimator(), glare: newGlareState()}}
+	// A window facing the sun at noon PDT in Mountain View.
+	s.DeviceConfigCustom = HWShadeConfig{ID: 16, Glare: &GlareConfig{Azimuth: 128, WindowBottom: 1, WindowTop: 2.5, Depth: 0.5}}
+	now := time.Date(2024, 6, 21, 19, 0, 0, 0, time.UTC)
+	want := s.DeviceConfigCustom.Glare.Level(astro.SunPosition(now, 37.42, -122.08))
+	if want <= 0 || want >= 100 {
+		t.Fatalf("expected a partially closed shade, got %v", want)
+	}
+	setCmd := fmt.Sprintf("#OUTPUT,16,1,%v\r\n", want)
+	mock.SetResponse(setCmd, "QNET> ")
+
+	if _, ok := s.Operations()["glare"]; !ok {
+		t.Errorf("glare operation is missing")
+	}
+	out := &bytes.Buffer{}
+	res, err := s.adjustGlare(ctx, now, devices.OperationArgs{Writer: out})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Moved || res.Level != want {
+		t.Errorf("unexpected result: %+v", res)
+	}
+	// A subsequent adjustment is suppressed unless forced.
+	res, err = s.adjustGlare(ctx, now.Add(time.Minute), devices.OperationArgs{Writer: out})
+	if err != nil || res.Moved {
+		t.Errorf("unexpected result: %+v, %v", res, err)
+	}
+	res, err = s.adjustGlare(ctx, now, devices.OperationArgs{Writer: out, Args: []string{"force"}})
+	if err != nil || !res.Moved {
+		t.Errorf("unexpected result: %+v, %v", res, err)
+	}
+	if got, want := sent(), []string{"admin\r\n",
+		"?SYSTEM,4\r\n", setCmd,
+		// The location is cached.
+		setCmd,
+	}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// Shades without glare configuration have no glare operation.
+	s.DeviceConfigCustom.Glare = nil
+	if _, ok := s.Operations()["glare"]; ok {
+		t.Errorf("unexpected glare operation")
+	}
+}
@@ -16,12 +16,23 @@ func NewController(typ string, opts devices.Options) (devices.Controller, error)
 func NewDevice(typ string, _ devices.Options) (devices.Device, error) {
 	switch typ {
 	case "shadegrp":
-		return &HWShadeGroup{hwShadeBase: hwShadeBase{}}, nil
+		return &HWShadeGroup{hwShadeBase: hwShadeBase{travel: newTravelEstimator(), glare: newGlareState()}}, nil
 	case "shade":
-		return &HWShade{hwShadeBase: hwShadeBase{}}, nil
+		return &HWShade{hwShadeBase: hwShadeBase{travel: newTravelEstimator(), glare: newGlareState()}}, nil
 	case "contact-closure-open-close":
 		return &ContactClosureOpenClose{}, nil
-
+	case "relay-momentary":
+		return &RelayMomentary{}, nil
+	case "relay-latched":
+		return &RelayLatched{}, nil
+	case "phantom-button":
+		return &PhantomButton{}, nil
+	case "keypad":
+		return &Keypad{}, nil
+	case "thermostat":
+		return &Thermostat{}, nil
+	case "scene":
+		return &Scene{}, nil
 	}
 	return nil, fmt.Errorf("unsupported lutron device type %s", typ)
 }
@@ -32,6 +43,12 @@ func SupportedDevices() devices.SupportedDevices {
 		"shade":                      NewDevice,
 		"contact-closure":            NewDevice,
 		"contact-closure-open-close": NewDevice,
+		"relay-momentary":            NewDevice,
+		"relay-latched":              NewDevice,
+		"phantom-button":             NewDevice,
+		"keypad":                     NewDevice,
+		"thermostat":                 NewDevice,
+		"scene":                      NewDevice,
 	}
 }
 
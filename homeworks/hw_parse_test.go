@@ -40,6 +40,19 @@ type config struct {
 	Devices     []devices.DeviceConfig     `yaml:"devices"`
 }
 
+func createSystem(ctx context.Context, t *testing.T, spec string) (map[string]devices.Controller, map[string]devices.Device, error) {
+	t.Helper()
+	var cfg config
+	if err := yaml.Unmarshal([]byte(spec), &cfg); err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+	return devices.CreateSystem(ctx,
+		cfg.Controllers,
+		cfg.Devices,
+		devices.WithDevices(homeworks.SupportedDevices()),
+		devices.WithControllers(homeworks.SupportedControllers()))
+}
+
 func TestHWParsing(t *testing.T) {
 	ctx := context.Background()
 	var cfg config
@@ -0,0 +1,73 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package homeworks
+
+import (
+	"context"
+
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/lutron/protocol"
+	"gopkg.in/yaml.v3"
+)
+
+// KeypadConfig represents the configuration for a physical keypad.
+type KeypadConfig struct {
+	// ID is the integration ID of the keypad.
+	ID int `yaml:"id"`
+	// Buttons maps button names to their component numbers.
+	Buttons map[string]int `yaml:"buttons"`
+}
+
+// Keypad names the buttons on a physical keypad so that events and
+// gestures for them can be reported by name. Unlike PhantomButton, it
+// does not support pressing the buttons since they are operated by
+// people rather than via the integration protocol.
+type Keypad struct {
+	devices.DeviceBase[KeypadConfig]
+	processor *QSProcessor
+}
+
+func (kp *Keypad) UnmarshalYAML(node *yaml.Node) error {
+	if err := node.Decode(&kp.DeviceConfigCustom); err != nil {
+		return err
+	}
+	cfg := &kp.DeviceConfigCustom
+	if err := validateID(kp.DeviceConfigCommon, "id", cfg.ID); err != nil {
+		return err
+	}
+	return validateButtons(kp.DeviceConfigCommon, cfg.Buttons)
+}
+
+func (kp *Keypad) integrationIDs() []integrationID {
+	return []integrationID{{"id", kp.DeviceConfigCustom.ID, protocol.DeviceCommands}}
+}
+
+func (kp *Keypad) SetController(c devices.Controller) {
+	kp.processor = c.Implementation().(*QSProcessor)
+}
+
+func (kp *Keypad) ControlledBy() devices.Controller {
+	return kp.processor
+}
+
+func (kp *Keypad) Operations() map[string]devices.Operation {
+	return map[string]devices.Operation{
+		"buttons": kp.listButtons,
+	}
+}
+
+func (kp *Keypad) OperationsHelp() map[string]string {
+	return map[string]string{
+		"buttons": "list the configured buttons",
+	}
+}
+
+func (kp *Keypad) keypadButtons() (int, []Button) {
+	return kp.DeviceConfigCustom.ID, sortedButtons(kp.DeviceConfigCustom.Buttons)
+}
+
+func (kp *Keypad) listButtons(_ context.Context, args devices.OperationArgs) (any, error) {
+	return listButtons(args.Writer, sortedButtons(kp.DeviceConfigCustom.Buttons)), nil
+}
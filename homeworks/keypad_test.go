@@ -0,0 +1,70 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package homeworks_test
+
+import (
+	"bytes"
+	"context"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/lutron/homeworks"
+)
+
+const keypadSpec = `
+controllers:
+  - name: home
+    type: homeworks-qs
+    keep_alive: 1m
+
+devices:
+  - name: hall
+    type: keypad
+    controller: home
+    id: 10
+    buttons:
+      lights: 3
+      shades: 1
+`
+
+func TestKeypad(t *testing.T) {
+	ctx := context.Background()
+	_, devs, err := createSystem(ctx, t, keypadSpec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kp := devs["hall"]
+	if got, want := kp.CustomConfig().(homeworks.KeypadConfig), (homeworks.KeypadConfig{
+		ID:      10,
+		Buttons: map[string]int{"lights": 3, "shades": 1},
+	}); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	// Physical keypads can only be listed, not pressed.
+	ops := kp.Operations()
+	if got, want := slices.Sorted(maps.Keys(ops)), []string{"buttons"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	out := &bytes.Buffer{}
+	res, err := ops["buttons"](ctx, devices.OperationArgs{Writer: out})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := res.([]homeworks.Button), []homeworks.Button{{"shades", 1}, {"lights", 3}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := out.String(), "shades: 1\nlights: 3\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	bad := strings.Replace(keypadSpec, "shades: 1", "shades: 0", 1)
+	if _, _, err := createSystem(ctx, t, bad); err == nil || !strings.Contains(err.Error(), "component must be a positive integer") {
+		t.Errorf("unexpected or missing error: %v", err)
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package homeworks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+
+	"cloudeng.io/logging/ctxlog"
+	"github.com/cosnicolaou/lutron/protocol"
+)
+
+// LEDBinding binds a named external boolean source, eg. 'alarm-armed' or
+// 'garage-open', to a keypad LED.
+type LEDBinding struct {
+	Source    string `yaml:"source"`
+	ID        int    `yaml:"id"`        // integration ID of the keypad.
+	Component int    `yaml:"component"` // component number of the LED.
+	Invert    bool   `yaml:"invert"`    // the LED is lit when the source is false.
+}
+
+func (b LEDBinding) state(on bool) protocol.LEDState {
+	if on != b.Invert {
+		return protocol.LEDOn
+	}
+	return protocol.LEDOff
+}
+
+// ledEventBuffer is the size of the buffer used for the LEDMirror's
+// subscription to monitoring events.
+const ledEventBuffer = 64
+
+type ledKey struct {
+	id, component int
+}
+
+// LEDMirror mirrors the state of external boolean sources onto keypad
+// LEDs. The processor knows nothing about such sources and will overwrite
+// the LEDs according to its own programming, or reset them on restart,
+// and hence LEDMirror re-asserts the LED states whenever monitoring
+// reports that an LED differs from its source or a new connection to the
+// processor is established.
+type LEDMirror struct {
+	processor *QSProcessor
+	bindings  []LEDBinding
+	byLED     map[ledKey]LEDBinding
+
+	mu      sync.Mutex
+	source  map[string]bool        // the last reported value of each source.
+	applied map[string]bool        // the value last set on all of a source's LEDs.
+	setting map[string]*sync.Mutex // serializes Set calls for each source.
+}
+
+// NewLEDMirror returns a new LEDMirror for the specified bindings. Each
+// LED may be bound to at most one source.
+func NewLEDMirror(p *QSProcessor, bindings []LEDBinding) (*LEDMirror, error) {
+	m := &LEDMirror{
+		processor: p,
+		bindings:  bindings,
+		byLED:     map[ledKey]LEDBinding{},
+		source:    map[string]bool{},
+		applied:   map[string]bool{},
+		setting:   map[string]*sync.Mutex{},
+	}
+	for i, b := range bindings {
+		if len(b.Source) == 0 {
+			return nil, fmt.Errorf("led binding %v: source must be specified", i)
+		}
+		if b.ID <= 0 || b.Component <= 0 {
+			return nil, fmt.Errorf("led binding %v: %v: id and component must be positive integers, not %v and %v", i, b.Source, b.ID, b.Component)
+		}
+		key := ledKey{b.ID, b.Component}
+		if prev, ok := m.byLED[key]; ok {
+			return nil, fmt.Errorf("led binding %v: %v: led %v,%v is already bound to %v", i, b.Source, b.ID, b.Component, prev.Source)
+		}
+		m.byLED[key] = b
+	}
+	return m, nil
+}
+
+// Set records the value of the named source and, if it differs from the
+// value last successfully set on all of the LEDs bound to it, sets those
+// LEDs. Calls to Set for the same source are serialized so that the LEDs
+// always reflect the value supplied by the last call.
+func (m *LEDMirror) Set(ctx context.Context, source string, on bool) error {
+	mu := m.sourceLock(source)
+	mu.Lock()
+	defer mu.Unlock()
+	m.mu.Lock()
+	m.source[source] = on
+	applied, known := m.applied[source]
+	m.mu.Unlock()
+	if known && applied == on {
+		return nil
+	}
+	var errs []error
+	for _, b := range m.bindings {
+		if b.Source == source {
+			errs = append(errs, m.setLED(ctx, b, on))
+		}
+	}
+	err := errors.Join(errs...)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		// Ensure that the next call to Set is not suppressed.
+		delete(m.applied, source)
+		return err
+	}
+	m.applied[source] = on
+	return nil
+}
+
+func (m *LEDMirror) sourceLock(source string) *sync.Mutex {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	mu, ok := m.setting[source]
+	if !ok {
+		mu = &sync.Mutex{}
+		m.setting[source] = mu
+	}
+	return mu
+}
+
+// Assert sets every LED whose source value is known.
+func (m *LEDMirror) Assert(ctx context.Context) error {
+	var errs []error
+	for _, b := range m.bindings {
+		if on, ok := m.value(b.Source); ok {
+			errs = append(errs, m.setLED(ctx, b, on))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m *LEDMirror) value(source string) (bool, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	on, ok := m.source[source]
+	return on, ok
+}
+
+func (m *LEDMirror) setLED(ctx context.Context, b LEDBinding, on bool) error {
+	ctx, sess, err := m.processor.session(ctx, b.ID)
+	if err != nil {
+		return err
+	}
+	defer sess.Release()
+	grp := slog.Group("lutron", "device", "led-mirror", "source", b.Source, "id", b.ID, "component", b.Component, "on", on)
+	ctx = ctxlog.WithAttributes(ctx, grp)
+	if err := protocol.SetLED(ctx, sess, b.ID, b.Component, b.state(on)); err != nil {
+		return fmt.Errorf("led %v,%v: %v: %w", b.ID, b.Component, b.Source, err)
+	}
+	return nil
+}
+
+// overwritten returns the binding for the LED reported by the event if
+// the LED's state differs from that of its source.
+func (m *LEDMirror) overwritten(ev protocol.Event) (LEDBinding, bool, bool) {
+	if ev.Type() != protocol.EventLED || len(ev.Fields) < 3 {
+		return LEDBinding{}, false, false
+	}
+	component, err := strconv.Atoi(ev.Fields[0])
+	if err != nil {
+		return LEDBinding{}, false, false
+	}
+	b, ok := m.byLED[ledKey{ev.ID, component}]
+	if !ok {
+		return LEDBinding{}, false, false
+	}
+	on, ok := m.value(b.Source)
+	if !ok {
+		return LEDBinding{}, false, false
+	}
+	state, err := strconv.Atoi(ev.Fields[2])
+	if err != nil || protocol.LEDState(state) == b.state(on) {
+		return LEDBinding{}, false, false
+	}
+	return b, on, true
+}
+
+// Run re-asserts the LED states whenever a new connection to the processor
+// is established and whenever monitoring reports that a bound LED differs
+// from its source, until the context is canceled. LED monitoring must
+// be enabled and QSProcessor.Monitor must be running for the latter.
+func (m *LEDMirror) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	connected := m.processor.Connected(ctx)
+	sub := m.processor.Events().Subscribe(ctx, ledEventBuffer)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-connected:
+			ctxlog.Info(ctx, "led mirror: new connection, re-asserting leds")
+			if err := m.Assert(ctx); err != nil {
+				ctxlog.Info(ctx, "led mirror: failed to re-assert leds", "err", err)
+			}
+		case ev := <-sub.Events():
+			b, on, ok := m.overwritten(ev)
+			if !ok {
+				continue
+			}
+			ctxlog.Info(ctx, "led mirror: led overwritten, re-asserting", "source", b.Source, "event", ev.String())
+			if err := m.setLED(ctx, b, on); err != nil {
+				ctxlog.Info(ctx, "led mirror: failed to re-assert led", "err", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package homeworks
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cosnicolaou/lutron/internal/testutil"
+	"github.com/cosnicolaou/lutron/protocol"
+)
+
+func waitForSent(t *testing.T, sent func() []string, n int) []string {
+	t.Helper()
+	for range 500 {
+		if s := sent(); len(s) >= n {
+			return s
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %v commands, got %q", n, sent())
+	return nil
+}
+
+func TestLEDMirror(t *testing.T) {
+	mock := testutil.NewMockTransport(testing.Verbose())
+	for _, state := range []string{"0", "1"} {
+		mock.SetResponse("#DEVICE,40,81,9,"+state+"\r\n", "QNET> ")
+		mock.SetResponse("#DEVICE,41,82,9,"+state+"\r\n", "QNET> ")
+	}
+	ctx, p := newMockProcessor(t, mock)
+	sent := recordSent(t, p)
+
+	if _, err := NewLEDMirror(p, []LEDBinding{
+		{Source: "alarm", ID: 40, Component: 81},
+		{Source: "garage", ID: 40, Component: 81},
+	}); err == nil {
+		t.Errorf("expected an error for an led bound to two sources")
+	}
+	m, err := NewLEDMirror(p, []LEDBinding{
+		{Source: "alarm", ID: 40, Component: 81},
+		{Source: "alarm", ID: 41, Component: 82, Invert: true},
+		{Source: "garage", ID: 41, Component: 81},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Set(ctx, "alarm", true); err != nil {
+		t.Fatal(err)
+	}
+	// Unchanged values are not resent.
+	if err := m.Set(ctx, "alarm", true); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := sent(), []string{
+		"admin\r\n",
+		"#DEVICE,40,81,9,1\r\n",
+		"#DEVICE,41,82,9,0\r\n",
+	}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	rctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- m.Run(rctx)
+	}()
+	for p.Events().Len() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// LEDs that match their source, are not bound or whose source
+	// is unknown are left alone, those that differ are re-asserted.
+	for _, ev := range []string{
+		"~DEVICE,40,81,9,1",
+		"~DEVICE,40,99,9,0",
+		"~DEVICE,41,81,9,1",
+		"~DEVICE,41,82,9,1",
+	} {
+		pev, err := protocol.ParseEvent(ev)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.Events().Publish(pev)
+	}
+	waitForSent(t, sent, 4)
+
+	// All LEDs with known sources are re-asserted on a new connection.
+	p.connects.notify()
+	got := waitForSent(t, sent, 6)
+	cancel()
+	if err := <-errCh; err != context.Canceled {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if want := []string{
+		"admin\r\n",
+		"#DEVICE,40,81,9,1\r\n",
+		"#DEVICE,41,82,9,0\r\n",
+		"#DEVICE,41,82,9,0\r\n",
+		"#DEVICE,40,81,9,1\r\n",
+		"#DEVICE,41,82,9,0\r\n",
+	}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestLEDMirrorSetFailure(t *testing.T) {
+	mock := testutil.NewMockTransport(testing.Verbose())
+	mock.SetResponse("#DEVICE,40,81,9,1\r\n", "QNET> ")
+	mock.SetResponse("#DEVICE,41,81,9,1\r\n", "~ERROR,6\r\nQNET> ")
+	ctx, p := newMockProcessor(t, mock)
+	sent := recordSent(t, p)
+	m, err := NewLEDMirror(p, []LEDBinding{
+		{Source: "alarm", ID: 40, Component: 81},
+		{Source: "alarm", ID: 41, Component: 81},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A value that could not be set on all of the LEDs is not treated
+	// as unchanged by subsequent calls.
+	for range 2 {
+		if err := m.Set(ctx, "alarm", true); err == nil {
+			t.Errorf("expected an error")
+		}
+	}
+	if got, want := sent(), []string{
+		"admin\r\n",
+		"#DEVICE,40,81,9,1\r\n", "#DEVICE,41,81,9,1\r\n",
+		"#DEVICE,40,81,9,1\r\n", "#DEVICE,41,81,9,1\r\n",
+	}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestLEDMirrorConcurrentSet(t *testing.T) {
+	mock := testutil.NewMockTransport(testing.Verbose())
+	for _, state := range []string{"0", "1"} {
+		mock.SetResponse("#DEVICE,40,81,9,"+state+"\r\n", "QNET> ")
+		mock.SetResponse("#DEVICE,41,81,9,"+state+"\r\n", "QNET> ")
+	}
+	ctx, p := newMockProcessor(t, mock)
+	sent := recordSent(t, p)
+	m, err := NewLEDMirror(p, []LEDBinding{
+		{Source: "alarm", ID: 40, Component: 81},
+		{Source: "alarm", ID: 41, Component: 81},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := m.Set(ctx, "alarm", i%2 == 0); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	// Each call sets both LEDs without being interleaved with another.
+	got := sent()[1:]
+	if len(got)%2 != 0 {
+		t.Fatalf("unexpected commands: %q", got)
+	}
+	for i := 0; i < len(got); i += 2 {
+		if got[i][len(got[i])-3] != got[i+1][len(got[i+1])-3] {
+			t.Errorf("interleaved commands: %q", got)
+			break
+		}
+	}
+	on, _ := m.value("alarm")
+	if want := map[bool]byte{false: '0', true: '1'}[on]; got[len(got)-1][len(got[len(got)-1])-3] != want {
+		t.Errorf("leds do not reflect the last value: %v: %q", on, got)
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package homeworks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"time"
+
+	"cloudeng.io/logging/ctxlog"
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/lutron/protocol"
+	"gopkg.in/yaml.v3"
+)
+
+// PhantomButtonConfig represents the configuration for the buttons of a
+// phantom (virtual) keypad, ie. one that exists only in the processor's
+// programming.
+type PhantomButtonConfig struct {
+	// ID is the integration ID of the phantom keypad.
+	ID int `yaml:"id"`
+	// Buttons maps button names to their component numbers.
+	Buttons map[string]int `yaml:"buttons"`
+	// HoldDuration is the time between the hold and release actions
+	// for the hold operation, it defaults to 1s.
+	HoldDuration time.Duration `yaml:"hold_duration"`
+}
+
+const (
+	defaultHoldDuration = time.Second
+	// Holds longer than this are almost certainly configuration errors.
+	maxHoldDuration = 10 * time.Second
+)
+
+// PhantomButton represents the buttons on a phantom keypad that are used
+// to trigger sequences programmed in the processor.
+type PhantomButton struct {
+	devices.DeviceBase[PhantomButtonConfig]
+	processor *QSProcessor
+}
+
+// Button represents a named keypad button.
+type Button struct {
+	Name      string `json:"name"`
+	Component int    `json:"component"`
+}
+
+func (pb *PhantomButton) UnmarshalYAML(node *yaml.Node) error {
+	if err := node.Decode(&pb.DeviceConfigCustom); err != nil {
+		return err
+	}
+	cfg := &pb.DeviceConfigCustom
+	if err := validateID(pb.DeviceConfigCommon, "id", cfg.ID); err != nil {
+		return err
+	}
+	if err := validateButtons(pb.DeviceConfigCommon, cfg.Buttons); err != nil {
+		return err
+	}
+	if cfg.HoldDuration < 0 || cfg.HoldDuration > maxHoldDuration {
+		return configError(pb.DeviceConfigCommon, "hold_duration", "must be in the range 0..%v, not %v", maxHoldDuration, cfg.HoldDuration)
+	}
+	if cfg.HoldDuration == 0 {
+		cfg.HoldDuration = defaultHoldDuration
+	}
+	return nil
+}
+
+func (pb *PhantomButton) integrationIDs() []integrationID {
+	return []integrationID{{"id", pb.DeviceConfigCustom.ID, protocol.DeviceCommands}}
+}
+
+func (pb *PhantomButton) SetController(c devices.Controller) {
+	pb.processor = c.Implementation().(*QSProcessor)
+}
+
+func (pb *PhantomButton) ControlledBy() devices.Controller {
+	return pb.processor
+}
+
+func (pb *PhantomButton) Operations() map[string]devices.Operation {
+	return map[string]devices.Operation{
+		"press":   pb.press,
+		"hold":    pb.hold,
+		"buttons": pb.listButtons,
+	}
+}
+
+func (pb *PhantomButton) OperationsHelp() map[string]string {
+	return map[string]string{
+		"press":   "press and release the named button",
+		"hold":    "press, hold and then release the named button",
+		"buttons": "list the configured buttons",
+	}
+}
+
+func validateButtons(cfg devices.DeviceConfigCommon, buttons map[string]int) error {
+	if len(buttons) == 0 {
+		return configError(cfg, "buttons", "at least one button must be specified")
+	}
+	for _, b := range sortedButtons(buttons) {
+		if b.Component <= 0 {
+			return configError(cfg, "buttons."+b.Name, "component must be a positive integer, not %v", b.Component)
+		}
+	}
+	return nil
+}
+
+// sortedButtons returns the supplied buttons sorted by component number.
+func sortedButtons(named map[string]int) []Button {
+	buttons := make([]Button, 0, len(named))
+	for name, c := range named {
+		buttons = append(buttons, Button{Name: name, Component: c})
+	}
+	slices.SortFunc(buttons, func(a, b Button) int {
+		if a.Component != b.Component {
+			return a.Component - b.Component
+		}
+		if a.Name < b.Name {
+			return -1
+		}
+		return 1
+	})
+	return buttons
+}
+
+func listButtons(w io.Writer, buttons []Button) []Button {
+	for _, b := range buttons {
+		fmt.Fprintf(w, "%v: %v\n", b.Name, b.Component)
+	}
+	return buttons
+}
+
+func (pb *PhantomButton) buttons() []Button {
+	return sortedButtons(pb.DeviceConfigCustom.Buttons)
+}
+
+func (pb *PhantomButton) keypadButtons() (int, []Button) {
+	return pb.DeviceConfigCustom.ID, pb.buttons()
+}
+
+func (pb *PhantomButton) button(args devices.OperationArgs) (Button, error) {
+	if len(args.Args) != 1 {
+		return Button{}, fmt.Errorf("a single button name must be specified")
+	}
+	c, ok := pb.DeviceConfigCustom.Buttons[args.Args[0]]
+	if !ok {
+		return Button{}, fmt.Errorf("unknown button: %q", args.Args[0])
+	}
+	return Button{Name: args.Args[0], Component: c}, nil
+}
+
+func (pb *PhantomButton) listButtons(_ context.Context, args devices.OperationArgs) (any, error) {
+	return listButtons(args.Writer, pb.buttons()), nil
+}
+
+// activate sends the supplied actions for the button followed by a
+// release. Rate limiting is only honoured before the first action is
+// sent, once it has been sent the release is always sent, even if the
+// context is canceled, so that the processor never sees a button that is
+// not released. The rate limiter is bypassed thereafter so that a press
+// is not turned into a hold.
+func (pb *PhantomButton) activate(ctx context.Context, op string, args devices.OperationArgs, hold time.Duration, actions ...int) (any, error) {
+	b, err := pb.button(args)
+	if err != nil {
+		return nil, err
+	}
+	id := pb.DeviceConfigCustom.ID
+	ctx, sess, err := pb.processor.session(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	defer sess.Release()
+	grp := slog.Group("lutron", "device", "phantom-button", "id", id, "button", b.Name, "component", b.Component, "op", op)
+	ctx = ctxlog.WithAttributes(ctx, grp)
+	if err := protocol.WaitForRateLimit(ctx, id); err != nil {
+		return b, err
+	}
+	var aerr error
+	for i, action := range actions {
+		if i > 0 && ctx.Err() != nil {
+			// Skip any remaining actions, waitUntil below will
+			// report the cancellation.
+			break
+		}
+		actx, cancel := uninterruptible(ctx)
+		aerr = protocol.DeviceAction(actx, sess, id, b.Component, action)
+		cancel()
+		if aerr != nil {
+			break
+		}
+	}
+	var werr error
+	if aerr == nil {
+		werr = waitUntil(ctx, time.Now().Add(hold))
+	}
+	rctx, cancel := uninterruptible(ctx)
+	defer cancel()
+	if err := protocol.DeviceAction(rctx, sess, id, b.Component, protocol.DeviceActionRelease); err != nil || aerr != nil {
+		return b, errors.Join(aerr, err)
+	}
+	if werr != nil {
+		return b, werr
+	}
+	fmt.Fprintf(args.Writer, "%v: %v\n", op, b.Name)
+	return b, nil
+}
+
+func (pb *PhantomButton) press(ctx context.Context, args devices.OperationArgs) (any, error) {
+	return pb.activate(ctx, "press", args, 0, protocol.DeviceActionPress)
+}
+
+func (pb *PhantomButton) hold(ctx context.Context, args devices.OperationArgs) (any, error) {
+	return pb.activate(ctx, "hold", args, pb.DeviceConfigCustom.HoldDuration, protocol.DeviceActionPress, protocol.DeviceActionHold)
+}
@@ -0,0 +1,101 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package homeworks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/lutron/internal/testutil"
+)
+
+func TestPhantomButton(t *testing.T) {
+	mock := testutil.NewMockTransport(testing.Verbose())
+	for _, action := range []string{"3", "4", "5"} {
+		mock.SetResponse("#DEVICE,30,2,"+action+"\r\n", "QNET> ")
+		mock.SetResponse("#DEVICE,30,5,"+action+"\r\n", "QNET> ")
+	}
+	ctx, p := newMockProcessor(t, mock)
+	sent := recordSent(t, p)
+	pb := &PhantomButton{processor: p}
+	pb.DeviceConfigCustom = PhantomButtonConfig{
+		ID:           30,
+		Buttons:      map[string]int{"party": 5, "movie": 2},
+		HoldDuration: 10 * time.Millisecond,
+	}
+	out := &bytes.Buffer{}
+
+	res, err := pb.listButtons(ctx, devices.OperationArgs{Writer: out})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := res.([]Button), []Button{{"movie", 2}, {"party", 5}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := out.String(), "movie: 2\nparty: 5\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if _, err := pb.press(ctx, devices.OperationArgs{Writer: out, Args: []string{"party"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pb.hold(ctx, devices.OperationArgs{Writer: out, Args: []string{"movie"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pb.press(ctx, devices.OperationArgs{Writer: out, Args: []string{"dinner"}}); err == nil {
+		t.Errorf("expected an error for an unknown button")
+	}
+
+	// The release is sent even if the hold is canceled.
+	pb.DeviceConfigCustom.HoldDuration = time.Hour
+	cctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := pb.hold(cctx, devices.OperationArgs{Writer: out, Args: []string{"party"}}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if got, want := sent(), []string{
+		"admin\r\n",
+		"#DEVICE,30,5,3\r\n", "#DEVICE,30,5,4\r\n",
+		"#DEVICE,30,2,3\r\n", "#DEVICE,30,2,5\r\n", "#DEVICE,30,2,4\r\n",
+		"#DEVICE,30,5,3\r\n", "#DEVICE,30,5,5\r\n", "#DEVICE,30,5,4\r\n",
+	}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestPhantomButtonCanceledDuringPress(t *testing.T) {
+	mock := testutil.NewMockTransport(testing.Verbose())
+	for _, action := range []string{"3", "4", "5"} {
+		mock.SetResponse("#DEVICE,30,5,"+action+"\r\n", "QNET> ")
+	}
+	ctx, p := newMockProcessor(t, mock)
+	sent := recordSent(t, p)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	cancelOnSend(p, "#DEVICE,30,5,3\r\n", cancel)
+	pb := &PhantomButton{processor: p}
+	pb.DeviceConfigCustom = PhantomButtonConfig{
+		ID:           30,
+		Buttons:      map[string]int{"party": 5},
+		HoldDuration: time.Hour,
+	}
+	if _, err := pb.hold(ctx, devices.OperationArgs{Writer: &bytes.Buffer{}, Args: []string{"party"}}); !errors.Is(err, context.Canceled) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	// The hold is abandoned but the button is always released.
+	if got, want := sent(), []string{
+		"admin\r\n",
+		"#DEVICE,30,5,3\r\n", "#DEVICE,30,5,4\r\n",
+	}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
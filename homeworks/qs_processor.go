@@ -6,7 +6,11 @@ package homeworks
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
 	"time"
 
 	"cloudeng.io/cmdutil/keystore"
@@ -15,7 +19,9 @@ import (
 	"github.com/cosnicolaou/automation/net/netutil"
 	"github.com/cosnicolaou/automation/net/streamconn"
 	"github.com/cosnicolaou/automation/net/streamconn/telnet"
+	"github.com/cosnicolaou/lutron/internal/sntp"
 	"github.com/cosnicolaou/lutron/protocol"
+	"github.com/cosnicolaou/lutron/transcript"
 
 	"gopkg.in/yaml.v3"
 )
@@ -25,6 +31,40 @@ type QSProcessorConfig struct {
 	KeepAlive time.Duration `yaml:"keep_alive"`
 	KeyID     string        `yaml:"key_id"`
 	Verbose   bool          `yaml:"verbose"`
+	// Record, if set, is the name of a file to which a transcript of
+	// all connections to the processor is appended.
+	Record string `yaml:"record"`
+	// Monitoring, if set, lists the types of monitoring to be enabled
+	// on the connection used by Monitor, all other types are disabled.
+	// Monitoring is not configured for the connection used for commands
+	// so that their responses are not interleaved with monitoring output.
+	// See protocol.MonitoringType for the supported names.
+	Monitoring []string `yaml:"monitoring"`
+	// NTPServer is the server used by the settime operation when
+	// setting the processor's time from NTP, it defaults to pool.ntp.org.
+	NTPServer string `yaml:"ntp_server"`
+	// StateStaleness is the age beyond which cached device state is
+	// refreshed by querying the processor, it defaults to 1 minute.
+	StateStaleness time.Duration `yaml:"state_staleness"`
+	// RateLimit limits the rate at which commands are sent to the
+	// processor, by default commands are not rate limited.
+	RateLimit protocol.RateLimit `yaml:"rate_limit"`
+}
+
+const (
+	defaultNTPServer      = "pool.ntp.org"
+	defaultStateStaleness = time.Minute
+)
+
+// defaultMonitoring is the set of monitoring types enabled on the
+// connection used by Monitor if none are configured.
+var defaultMonitoring = []protocol.MonitoringType{
+	protocol.MonitorButton,
+	protocol.MonitorLED,
+	protocol.MonitorZone,
+	protocol.MonitorOccupancy,
+	protocol.MonitorSysvar,
+	protocol.MonitorHVAC,
 }
 
 type QSProcessor struct {
@@ -32,11 +72,23 @@ type QSProcessor struct {
 
 	mgr      *streamconn.SessionManager
 	ondemand *netutil.OnDemandConnection[streamconn.Transport, *QSProcessor]
+	dial     func(ctx context.Context, addr string, timeout time.Duration) (streamconn.Transport, error)
+
+	monitoring []protocol.MonitoringType
+	state      *StateCache
+	events     *EventHub
+	connects   notifier
+	limiter    *protocol.RateLimiter
+	latlong    latLongCache
+	configErr  error
 }
 
 func NewQSProcessor(_ devices.Options) *QSProcessor {
 	p := &QSProcessor{
-		mgr: &streamconn.SessionManager{},
+		mgr:    &streamconn.SessionManager{},
+		dial:   telnet.Dial,
+		state:  NewStateCache(defaultStateStaleness),
+		events: NewEventHub(),
 	}
 	p.ondemand = netutil.NewOnDemandConnection(p)
 	return p
@@ -50,9 +102,48 @@ func (p *QSProcessor) UnmarshalYAML(node *yaml.Node) error {
 		return fmt.Errorf("keep_alive must be specified")
 	}
 	p.ondemand.SetKeepAlive(p.ControllerConfigCustom.KeepAlive)
+	if mon := p.ControllerConfigCustom.Monitoring; mon != nil {
+		types, err := protocol.ParseMonitoringTypes(mon)
+		if err != nil {
+			return err
+		}
+		p.monitoring = types
+	}
+	if st := p.ControllerConfigCustom.StateStaleness; st != 0 {
+		p.state.SetStaleness(st)
+	}
+	rl := p.ControllerConfigCustom.RateLimit
+	if rl.Rate < 0 || rl.Burst < 0 || rl.MinGap < 0 {
+		return fmt.Errorf("rate_limit: rate, burst and min_gap must not be negative")
+	}
+	if rl != (protocol.RateLimit{}) {
+		p.limiter = protocol.NewRateLimiter(rl)
+	}
 	return nil
 }
 
+// SetSystem implements devices.Controller. It also checks the devices
+// controlled by this processor for configuration problems that span
+// multiple devices, such as duplicate integration IDs. Any such problems
+// are logged, using the default logger since SetSystem has no context,
+// and are available via ConfigError.
+func (p *QSProcessor) SetSystem(s devices.System) {
+	p.ControllerBase.SetSystem(s)
+	p.configErr = validateDevices(s.Devices, func(dev devices.Device) bool {
+		return dev.ControlledBy() == devices.Controller(p)
+	})
+	if p.configErr != nil {
+		slog.Error("lutron: invalid device configuration", "controller", p.Name, "err", p.configErr)
+	}
+}
+
+// ConfigError returns any configuration problems found by SetSystem for
+// the devices controlled by this processor. The verify operation reports
+// these problems in addition to those found on the processor itself.
+func (p *QSProcessor) ConfigError() error {
+	return p.configErr
+}
+
 func (p *QSProcessor) Implementation() any {
 	return p
 }
@@ -66,14 +157,39 @@ func (p *QSProcessor) runOperation(ctx context.Context, op func(context.Context,
 	return op(ctx, sess, args)
 }
 
-func (p *QSProcessor) contactClosurePulse(ctx context.Context, id []byte, pulse, interval time.Duration, l0, l1 byte) (any, error) {
-	ctx, sess, err := p.session(ctx)
+// releaseTimeout is the time allowed for each of the commands that start
+// and end a contact closure pulse or phantom button press.
+const releaseTimeout = 10 * time.Second
+
+// PulseResult represents the outcome of a contact closure pulse.
+type PulseResult struct {
+	ID        int           `json:"id"`
+	Requested time.Duration `json:"requested"`
+	// Actual is the time between the processor acknowledging the
+	// commands that start and end the pulse.
+	Actual time.Duration `json:"actual"`
+	// Canceled is true if the pulse was ended early because the
+	// context was canceled.
+	Canceled bool `json:"canceled,omitempty"`
+}
+
+// contactClosurePulse sets the contact closure output to l0 for the pulse
+// duration and then to l1. The command that ends the pulse is always sent,
+// even if the context is canceled during the pulse, so that a relay is
+// never left energised.
+func (p *QSProcessor) contactClosurePulse(ctx context.Context, id int, pulse time.Duration, l0, l1 byte) (PulseResult, error) {
+	result := PulseResult{ID: id, Requested: pulse}
+	ctx, sess, err := p.session(ctx, id)
 	if err != nil {
-		return nil, err
+		return result, err
 	}
 	defer sess.Release()
-	pars := make([]byte, 0, 32)
-	pars = append(pars, id...)
+	// Cancellation and rate limiting are only honoured before the pulse
+	// is started, once started the pulse must always be ended.
+	if err := protocol.WaitForRateLimit(ctx, id); err != nil {
+		return result, err
+	}
+	pars := strconv.AppendInt(make([]byte, 0, 32), int64(id), 10)
 	pars = append(pars, ',', '1', ',', l0)
 	// Ignore any response since the response may refer
 	// to integration IDs that don't match the request.
@@ -82,15 +198,69 @@ func (p *QSProcessor) contactClosurePulse(ctx context.Context, id []byte, pulse,
 	// sent to the visor control, but the system issues
 	// monitoring commands that refer to the integration IDs
 	// of the devices connected to the visor control.
-	err = protocol.NewCommand(protocol.OutputCommands, true, pars).Invoke(ctx, sess)
+	sctx, cancel := uninterruptible(ctx)
+	serr := protocol.NewCommand(protocol.OutputCommands, true, pars).Invoke(sctx, sess)
+	cancel()
+	start := time.Now()
+	if serr == nil {
+		if err := waitUntil(ctx, start.Add(pulse)); err != nil {
+			result.Canceled = true
+			ctxlog.Info(ctx, "contact closure pulse canceled", "err", err)
+		}
+	}
+	// Always end the pulse, even if the command that started it
+	// failed since it may still have been acted on.
+	rctx, cancel := uninterruptible(ctx)
+	defer cancel()
+	pars[len(pars)-1] = l1
+	err = protocol.NewCommand(protocol.OutputCommands, true, pars).Invoke(rctx, sess)
+	result.Actual = time.Since(start)
+	if serr != nil {
+		return result, errors.Join(serr, err)
+	}
+	if err == nil && result.Canceled {
+		err = ctx.Err()
+	}
+	return result, err
+}
+
+// uninterruptible returns a context, derived from ctx, that is not
+// canceled when ctx is and that bypasses the rate limiter, for commands
+// that must be sent without interruption, such as those that start and
+// end a pulse. The context times out after releaseTimeout.
+func uninterruptible(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx = protocol.WithRateLimiter(context.WithoutCancel(ctx), nil)
+	return context.WithTimeout(ctx, releaseTimeout)
+}
+
+// Batch sends all of the supplied commands back-to-back using a single
+// session and returns the result of each command. The returned error is
+// only non-nil if a session could not be obtained, use Results.Err
+// to determine if any of the commands failed.
+func (p *QSProcessor) Batch(ctx context.Context, cmds ...protocol.Command) (protocol.Results, error) {
+	ids := make([]int, len(cmds))
+	for i, cmd := range cmds {
+		ids[i] = cmd.IntegrationID()
+	}
+	ctx, sess, err := p.session(ctx, ids...)
 	if err != nil {
 		return nil, err
 	}
-	time.Sleep(pulse)
-	pars[len(pars)-1] = l1
-	err = protocol.NewCommand(protocol.OutputCommands, true, pars).Invoke(ctx, sess)
-	time.Sleep(interval) // Seems to need a delay between successive commands.
-	return nil, err
+	defer sess.Release()
+	ctx = ctxlog.WithAttributes(ctx, "batch", len(cmds))
+	return protocol.Batch(ctx, sess, cmds...), nil
+}
+
+// Exec sends the supplied command line to the processor and returns the
+// unparsed response, without the trailing prompt. It is intended for
+// relaying commands from other clients, see protocol.Raw.
+func (p *QSProcessor) Exec(ctx context.Context, line string) (string, error) {
+	ctx, sess, err := p.session(ctx, protocol.RawIntegrationID(line))
+	if err != nil {
+		return "", err
+	}
+	defer sess.Release()
+	return protocol.Raw(ctx, sess, line)
 }
 
 func (p *QSProcessor) getTime(ctx context.Context, sess *streamconn.Session, args devices.OperationArgs) (any, error) {
@@ -117,18 +287,26 @@ func (p *QSProcessor) getLocation(ctx context.Context, sess *streamconn.Session,
 func (p *QSProcessor) getSuntimes(ctx context.Context, sess *streamconn.Session, args devices.OperationArgs) (any, error) {
 	rise, set, err := protocol.GetSunriseSunset(ctx, sess)
 	if err == nil {
-		fmt.Fprintf(args.Writer, "sunrise: %v, sunset: %v\n",
-			rise.Format("15:04:05"), set.Format("15:04:05"))
+		fmt.Fprintf(args.Writer, "sunrise: %v, sunset: %v\n", rise, set)
 	}
 	return struct {
-		SunRise string `json:"sunrise"`
-		SunSet  string `json:"sunset"`
+		SunRise time.Time `json:"sunrise"`
+		SunSet  time.Time `json:"sunset"`
 	}{
-		SunRise: rise.Format("15:04:05"),
-		SunSet:  set.Format("15:04:05"),
+		SunRise: rise,
+		SunSet:  set,
 	}, err
 }
 
+func (p *QSProcessor) getSystemInfo(ctx context.Context, sess *streamconn.Session, args devices.OperationArgs) (any, error) {
+	info, err := protocol.GetSystemInfo(ctx, sess)
+	if err == nil {
+		fmt.Fprintf(args.Writer, "time: %v\nlocation: %v %v\nsunrise: %v\nsunset: %v\nos: %v\n",
+			info.Time, info.Latitude, info.Longitude, info.Sunrise, info.Sunset, info.OSRevision)
+	}
+	return info, err
+}
+
 func (p *QSProcessor) getOSVersion(ctx context.Context, sess *streamconn.Session, args devices.OperationArgs) (any, error) {
 	osv, err := protocol.GetVersion(ctx, sess)
 	if err == nil {
@@ -139,6 +317,136 @@ func (p *QSProcessor) getOSVersion(ctx context.Context, sess *streamconn.Session
 	}{OSVersion: osv}, err
 }
 
+// hostTimeOffset returns the offset to be applied to the host's clock
+// for the requested time source, either host or ntp.
+func (p *QSProcessor) hostTimeOffset(ctx context.Context, args []string) (string, time.Duration, error) {
+	if len(args) == 0 || args[0] == "host" {
+		return "host", 0, nil
+	}
+	if args[0] != "ntp" {
+		return "", 0, fmt.Errorf("unsupported time source: %q, must be host or ntp", args[0])
+	}
+	server := p.ControllerConfigCustom.NTPServer
+	if len(args) > 1 {
+		server = args[1]
+	}
+	if len(server) == 0 {
+		server = defaultNTPServer
+	}
+	now, err := sntp.Query(ctx, server)
+	if err != nil {
+		return "", 0, fmt.Errorf("ntp: %v: %w", server, err)
+	}
+	return "ntp:" + server, time.Until(now), nil
+}
+
+func (p *QSProcessor) setTime(ctx context.Context, sess *streamconn.Session, args devices.OperationArgs) (any, error) {
+	source, offset, err := p.hostTimeOffset(ctx, args.Args)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := protocol.GetTimeZone(ctx, sess)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().Add(offset).Round(time.Second).In(loc)
+	if err := protocol.SetDate(ctx, sess, now); err != nil {
+		return nil, err
+	}
+	if err := protocol.SetTime(ctx, sess, now); err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(args.Writer, "settime: %v (%v)\n", now, source)
+	return struct {
+		Time   string `json:"time"`
+		Source string `json:"source"`
+	}{Time: now.String(), Source: source}, nil
+}
+
+func (p *QSProcessor) setLocation(ctx context.Context, sess *streamconn.Session, args devices.OperationArgs) (any, error) {
+	var lat, long float64
+	switch len(args.Args) {
+	case 0:
+		loc := p.System().Location
+		if loc.Latitude == 0 && loc.Longitude == 0 {
+			return nil, fmt.Errorf("no latitude and longitude specified or configured for the system")
+		}
+		lat, long = loc.Latitude, loc.Longitude
+	case 2:
+		var err error
+		if lat, err = strconv.ParseFloat(args.Args[0], 64); err != nil {
+			return nil, fmt.Errorf("failed to parse latitude: %v", err)
+		}
+		if long, err = strconv.ParseFloat(args.Args[1], 64); err != nil {
+			return nil, fmt.Errorf("failed to parse longitude: %v", err)
+		}
+	default:
+		return nil, fmt.Errorf("must specify both latitude and longitude")
+	}
+	if err := protocol.SetLatLong(ctx, sess, lat, long); err != nil {
+		return nil, err
+	}
+	p.latlong.reset()
+	fmt.Fprintf(args.Writer, "setlocation: %v %v\n", lat, long)
+	return struct {
+		Latitude  float64 `json:"latitude"`
+		Longitude float64 `json:"longitude"`
+	}{Latitude: lat, Longitude: long}, nil
+}
+
+// ClockDrift represents the difference between the processor's clock
+// and that of the host.
+type ClockDrift struct {
+	Processor time.Time     `json:"processor"`
+	Host      time.Time     `json:"host"`
+	Skew      time.Duration `json:"skew"` // processor - host.
+}
+
+// checkDrift compares the processor's clock to that of the host, an
+// optional argument specifies the maximum allowed skew beyond which
+// an error is returned.
+func (p *QSProcessor) checkDrift(ctx context.Context, sess *streamconn.Session, args devices.OperationArgs) (any, error) {
+	var threshold time.Duration
+	if len(args.Args) > 0 {
+		var err error
+		if threshold, err = time.ParseDuration(args.Args[0]); err != nil {
+			return nil, fmt.Errorf("failed to parse maximum skew: %v", err)
+		}
+	}
+	before := time.Now()
+	pt, err := protocol.GetTime(ctx, sess)
+	if err != nil {
+		return nil, err
+	}
+	after := time.Now()
+	// The processor only reports the time to the nearest second, so use
+	// the midpoint of the request to minimise the impact of latency.
+	host := before.Add(after.Sub(before) / 2).Round(time.Second).In(pt.Location())
+	drift := ClockDrift{Processor: pt, Host: host, Skew: pt.Sub(host)}
+	fmt.Fprintf(args.Writer, "processor: %v, host: %v, skew: %v\n", drift.Processor, drift.Host, drift.Skew)
+	if threshold > 0 && (drift.Skew > threshold || drift.Skew < -threshold) {
+		return drift, fmt.Errorf("processor clock skew of %v exceeds %v", drift.Skew, threshold)
+	}
+	return drift, nil
+}
+
+func (p *QSProcessor) getMonitoring(ctx context.Context, sess *streamconn.Session, args devices.OperationArgs) (any, error) {
+	enabled := map[string]bool{}
+	for _, typ := range protocol.MonitoringTypes() {
+		on, err := protocol.GetMonitoring(ctx, sess, typ)
+		if err != nil {
+			if errors.Is(err, protocol.ErrAccessPointInvalidActionNumber) || errors.Is(err, protocol.ErrAccessPointParemeterOutOfRange) {
+				// Not all systems support all monitoring types.
+				continue
+			}
+			return enabled, err
+		}
+		fmt.Fprintf(args.Writer, "%v: %v\n", typ, on)
+		enabled[typ.String()] = on
+	}
+	return enabled, nil
+}
+
 func (p *QSProcessor) Operations() map[string]devices.Operation {
 	return map[string]devices.Operation{
 		"gettime": func(ctx context.Context, args devices.OperationArgs) (any, error) {
@@ -153,24 +461,70 @@ func (p *QSProcessor) Operations() map[string]devices.Operation {
 		"os_version": func(ctx context.Context, args devices.OperationArgs) (any, error) {
 			return p.runOperation(ctx, p.getOSVersion, args)
 		},
+		"getsysteminfo": func(ctx context.Context, args devices.OperationArgs) (any, error) {
+			return p.runOperation(ctx, p.getSystemInfo, args)
+		},
+		"getmonitoring": func(ctx context.Context, args devices.OperationArgs) (any, error) {
+			return p.runOperation(ctx, p.getMonitoring, args)
+		},
+		"settime": func(ctx context.Context, args devices.OperationArgs) (any, error) {
+			return p.runOperation(ctx, p.setTime, args)
+		},
+		"setlocation": func(ctx context.Context, args devices.OperationArgs) (any, error) {
+			return p.runOperation(ctx, p.setLocation, args)
+		},
+		"checkdrift": func(ctx context.Context, args devices.OperationArgs) (any, error) {
+			return p.runOperation(ctx, p.checkDrift, args)
+		},
+		"computesuntimes": p.computeSunTimes,
+		"checksuntimes": func(ctx context.Context, args devices.OperationArgs) (any, error) {
+			lat, long, err := p.location(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return p.runOperation(ctx, func(ctx context.Context, sess *streamconn.Session, args devices.OperationArgs) (any, error) {
+				return p.checkSunTimes(ctx, lat, long, sess, args)
+			}, args)
+		},
+		"verify":   p.verify,
+		"discover": p.discover,
 	}
 }
 
 func (*QSProcessor) OperationsHelp() map[string]string {
 	return map[string]string{
-		"gettime":     "get the current time, date and timezone",
-		"getlocation": "get the current location in latitude and longitude",
-		"getsuntimes": "get the current sunrise and sunset times in local time",
-		"os_version":  "get the OS version running on QS processor",
+		"gettime":         "get the current time, date and timezone",
+		"getlocation":     "get the current location in latitude and longitude",
+		"getsuntimes":     "get today's sunrise and sunset times in the processor's timezone",
+		"os_version":      "get the OS version running on QS processor",
+		"getsysteminfo":   "get the time, location, sunrise/sunset times and OS version of the QS processor",
+		"getmonitoring":   "get the monitoring types enabled for the current connection",
+		"settime":         "set the processor's date and time from the host clock (host, the default) or from NTP (ntp [server])",
+		"setlocation":     "set the processor's latitude and longitude, from the arguments or the system configuration",
+		"checkdrift":      "compare the processor's clock to the host's, an optional maximum skew (eg. 30s) may be specified",
+		"computesuntimes": "compute today's, or the specified date's (YYYY-MM-DD), sunrise, sunset, twilight and solar noon times locally, using the configured location or that reported by the processor",
+		"checksuntimes":   "compare the processor's sunrise and sunset times to those computed locally, an optional tolerance (default 5m) may be specified",
+		"verify":          "verify that the integration ids used by all configured devices exist and are of the expected type",
+		"discover":        "scan a range of integration ids (default 1 255) to build an inventory of outputs, devices and shade groups",
 	}
 }
 
-func (p *QSProcessor) Connect(ctx context.Context, idle netutil.IdleReset) (streamconn.Transport, error) {
-	conn, err := telnet.Dial(ctx, p.ControllerConfigCustom.IPAddress, p.Timeout)
+// dialAndLogin dials the processor, logs in and configures the specified
+// monitoring types, if any.
+func (p *QSProcessor) dialAndLogin(ctx context.Context, idle netutil.IdleReset, mgr *streamconn.SessionManager, monitoring []protocol.MonitoringType) (streamconn.Transport, error) {
+	conn, err := p.dial(ctx, p.ControllerConfigCustom.IPAddress, p.Timeout)
 	if err != nil {
 		return nil, err
 	}
-	ctx, session := p.mgr.NewWithContext(ctx, conn, idle)
+	if rec := p.ControllerConfigCustom.Record; len(rec) > 0 {
+		f, err := os.OpenFile(rec, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			conn.Close(ctx)
+			return nil, fmt.Errorf("failed to open transcript file: %w", err)
+		}
+		conn = transcript.NewRecorder(conn, f)
+	}
+	ctx, session := mgr.NewWithContext(ctx, conn, idle)
 	defer session.Release()
 
 	// Authenticate
@@ -179,19 +533,42 @@ func (p *QSProcessor) Connect(ctx context.Context, idle netutil.IdleReset) (stre
 		conn.Close(ctx)
 		return nil, err
 	}
+	if monitoring != nil {
+		if err := protocol.ConfigureMonitoring(ctx, session, monitoring...); err != nil {
+			conn.Close(ctx)
+			return nil, err
+		}
+	}
+	p.connects.notify()
 	return conn, nil
 }
 
+func (p *QSProcessor) Connect(ctx context.Context, idle netutil.IdleReset) (streamconn.Transport, error) {
+	return p.dialAndLogin(ctx, idle, p.mgr, nil)
+}
+
 func (p *QSProcessor) Disconnect(ctx context.Context, conn streamconn.Transport) error {
 	return conn.Close(ctx)
 }
 
 // Session returns an authenticated session to the QS processor. If
 // an error is encountered then an error session is returned.
-// It also adds the protocol name to the context for logging purposes.
+// It also adds the protocol name to the context for logging purposes
+// and the processor's rate limiter, if any, so that all commands issued
+// using the returned context are rate limited. The minimum gap for
+// each of the supplied integration IDs is waited for before the session
+// is acquired so that waiting for one ID does not block commands for
+// other IDs, see protocol.ReserveIntegrationIDs.
 // The session must be released when the operation is complete.
-func (p *QSProcessor) session(ctx context.Context) (context.Context, *streamconn.Session, error) {
+func (p *QSProcessor) session(ctx context.Context, ids ...int) (context.Context, *streamconn.Session, error) {
 	ctx = ctxlog.WithAttributes(ctx, "protocol", "homeworks-qs")
+	if p.limiter != nil {
+		ctx = protocol.WithRateLimiter(ctx, p.limiter)
+		var err error
+		if ctx, err = protocol.ReserveIntegrationIDs(ctx, ids...); err != nil {
+			return ctx, nil, err
+		}
+	}
 	conn, idle, err := p.ondemand.Connection(ctx)
 	if err != nil {
 		return ctx, nil, err
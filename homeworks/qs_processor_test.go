@@ -0,0 +1,282 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package homeworks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"cloudeng.io/datetime"
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/lutron/astro"
+	"github.com/cosnicolaou/lutron/internal/testutil"
+	"github.com/cosnicolaou/lutron/protocol"
+	"gopkg.in/yaml.v3"
+)
+
+func TestBatch(t *testing.T) {
+	mock := testutil.NewMockTransport(testing.Verbose())
+	mock.SetResponse("#SHADEGRP,1,1,50\r\n", "QNET> ")
+	mock.SetResponse("#SHADEGRP,2,1,50\r\n", "~ERROR,2\r\nQNET> ")
+	mock.SetResponse("?OUTPUT,3,1\r\n", "~OUTPUT,3,1,25.00\r\nQNET> ")
+	ctx, p := newMockProcessor(t, mock)
+
+	results, err := p.Batch(ctx,
+		protocol.NewCommand(protocol.ShadeGroupCommands, true, []byte("1,1,50")),
+		protocol.NewCommand(protocol.ShadeGroupCommands, true, []byte("2,1,50")),
+		protocol.NewCommand(protocol.OutputCommands, false, []byte("3,1")),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(results), 3; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if err := results[0].Err; err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := results[1].Err; !errors.Is(err, protocol.ErrAccessPointObjectDoesNotExist) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if got, want := results[2].Response, "25.00"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := results.Failed(), 1; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if err := results.Err(); !errors.Is(err, protocol.ErrAccessPointObjectDoesNotExist) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestMonitoringAtLogin(t *testing.T) {
+	mock := testutil.NewMockTransport(testing.Verbose())
+	mock.SetResponse("#MONITORING,255,2\r\n", "~MONITORING,255,2\r\nQNET> ")
+	mock.SetResponse("#MONITORING,5,1\r\n", "~MONITORING,5,1\r\nQNET> ")
+	mock.SetResponse("?OUTPUT,3,1\r\n", "~OUTPUT,3,1,25.00\r\nQNET> ")
+	ctx, p := newMockProcessor(t, mock)
+	p.monitoring = []protocol.MonitoringType{protocol.MonitorZone}
+	sent := recordSent(t, p)
+
+	// The configured monitoring is only enabled for Monitor's connection,
+	// not for the connection used for commands.
+	results, err := p.Batch(ctx, protocol.NewCommand(protocol.OutputCommands, false, []byte("3,1")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := results.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := sent(), []string{
+		"admin\r\n",
+		"?OUTPUT,3,1\r\n",
+	}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	mctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- p.Monitor(mctx)
+	}()
+	want := []string{
+		"admin\r\n",
+		"?OUTPUT,3,1\r\n",
+		"admin\r\n",
+		"#MONITORING,255,2\r\n",
+		"#MONITORING,5,1\r\n",
+	}
+	deadline := time.After(5 * time.Second)
+	for !slices.Equal(sent(), want) {
+		select {
+		case err := <-errCh:
+			t.Fatalf("got %q, want %q: %v", sent(), want, err)
+		case <-deadline:
+			t.Fatalf("got %q, want %q", sent(), want)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	cancel()
+	if err := <-errCh; !errors.Is(err, context.Canceled) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestCheckDrift(t *testing.T) {
+	mock := testutil.NewMockTransport(testing.Verbose())
+	mock.SetResponse("?SYSTEM,1\r\n", "~SYSTEM,1,18:33:16\r\nQNET> ")
+	mock.SetResponse("?SYSTEM,2\r\n", "~SYSTEM,2,11/17/2024\r\nQNET> ")
+	mock.SetResponse("?SYSTEM,5\r\n", "~SYSTEM,5,-8:00\r\nQNET> ")
+	ctx, p := newMockProcessor(t, mock)
+
+	out := &bytes.Buffer{}
+	op := p.Operations()["checkdrift"]
+	res, err := op(ctx, devices.OperationArgs{Writer: out})
+	if err != nil {
+		t.Fatal(err)
+	}
+	drift := res.(ClockDrift)
+	if want := time.Date(2024, 11, 17, 18, 33, 16, 0, time.FixedZone("", -8*60*60)); !drift.Processor.Equal(want) {
+		t.Errorf("got %v, want %v", drift.Processor, want)
+	}
+	if got, want := drift.Skew, drift.Processor.Sub(drift.Host); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	_, err = op(ctx, devices.OperationArgs{Writer: out, Args: []string{"1m"}})
+	if err == nil || !strings.Contains(err.Error(), "exceeds 1m0s") {
+		t.Errorf("unexpected or missing error: %v", err)
+	}
+}
+
+func TestSunTimes(t *testing.T) {
+	mock := testutil.NewMockTransport(testing.Verbose())
+	mock.SetResponse("?SYSTEM,2\r\n", "~SYSTEM,2,11/17/2024\r\nQNET> ")
+	mock.SetResponse("?SYSTEM,4\r\n", "~SYSTEM,4,37.42,-122.08\r\nQNET> ")
+	mock.SetResponse("?SYSTEM,5\r\n", "~SYSTEM,5,-8:00\r\nQNET> ")
+	mock.SetResponse("?SYSTEM,6\r\n", "~SYSTEM,6,16:55:03\r\nQNET> ")
+	mock.SetResponse("?SYSTEM,7\r\n", "~SYSTEM,7,06:51:10\r\nQNET> ")
+	ctx, p := newMockProcessor(t, mock)
+	sent := recordSent(t, p)
+	out := &bytes.Buffer{}
+
+	res, err := p.Operations()["computesuntimes"](ctx, devices.OperationArgs{Writer: out, Args: []string{"2024-11-17"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := res.(astro.SunTimes)
+	if got, want := st.Sunrise.Format(time.DateOnly), "2024-11-17"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	check := p.Operations()["checksuntimes"]
+	res, err = check(ctx, devices.OperationArgs{Writer: out})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stc := res.(SunTimesCheck)
+	for _, d := range []time.Duration{stc.SunriseDiff, stc.SunsetDiff} {
+		if d < -time.Minute || d > time.Minute {
+			t.Errorf("unexpected difference: %+v", stc)
+		}
+	}
+	if got, want := stc.Sunrise, time.Date(2024, 11, 17, 6, 51, 10, 0, time.FixedZone("", -8*60*60)); !got.Equal(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	mock.SetResponse("?SYSTEM,6\r\n", "~SYSTEM,6,17:30:00\r\nQNET> ")
+	_, err = check(ctx, devices.OperationArgs{Writer: out})
+	if err == nil || !strings.Contains(err.Error(), "more than 5m0s") {
+		t.Errorf("unexpected or missing error: %v", err)
+	}
+	if _, err = check(ctx, devices.OperationArgs{Writer: out, Args: []string{"1h"}}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// The processor's location is only queried once.
+	queries := 0
+	for _, c := range sent() {
+		if c == "?SYSTEM,4\r\n" {
+			queries++
+		}
+	}
+	if queries != 1 {
+		t.Errorf("location queried %v times", queries)
+	}
+
+	// A configured location takes precedence.
+	p.SetSystem(devices.System{Location: devices.Location{Place: datetime.Place{Latitude: 51.5, Longitude: -0.13}}})
+	lat, long, err := p.location(ctx)
+	if err != nil || lat != 51.5 || long != -0.13 {
+		t.Errorf("unexpected location: %v, %v, %v", lat, long, err)
+	}
+}
+
+func TestExec(t *testing.T) {
+	mock := testutil.NewMockTransport(testing.Verbose())
+	mock.SetResponse("?OUTPUT,3,1\r\n", "~OUTPUT,3,1,25.00\r\nQNET> ")
+	mock.SetResponse("?OUTPUT,4,1\r\n", "~ERROR,2\r\nQNET> ")
+	ctx, p := newMockProcessor(t, mock)
+	for _, tc := range []struct {
+		line, response string
+	}{
+		{"?OUTPUT,3,1", "~OUTPUT,3,1,25.00\r\n"},
+		{"?OUTPUT,4,1", "~ERROR,2\r\n"},
+	} {
+		resp, err := p.Exec(ctx, tc.line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := resp, tc.response; got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	}
+}
+
+func TestRateLimitConfig(t *testing.T) {
+	for _, tc := range []struct {
+		spec    string
+		limited bool
+		err     string
+	}{
+		{"keep_alive: 1m", false, ""},
+		{"keep_alive: 1m\nrate_limit:\n  rate: 5\n  burst: 3\n  min_gap: 250ms", true, ""},
+		{"keep_alive: 1m\nrate_limit:\n  min_gap: 250ms", true, ""},
+		{"keep_alive: 1m\nrate_limit:\n  rate: -1", false, "must not be negative"},
+	} {
+		p := NewQSProcessor(devices.Options{})
+		err := yaml.Unmarshal([]byte(tc.spec), p)
+		if len(tc.err) > 0 {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("%q: unexpected or missing error: %v", tc.spec, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tc.spec, err)
+			continue
+		}
+		if got, want := p.limiter != nil, tc.limited; got != want {
+			t.Errorf("%q: got %v, want %v", tc.spec, got, want)
+		}
+	}
+}
+
+func TestRateLimitPerID(t *testing.T) {
+	mock := testutil.NewMockTransport(testing.Verbose())
+	mock.SetResponse("#OUTPUT,3,1,50\r\n", "QNET> ")
+	mock.SetResponse("#OUTPUT,4,1,50\r\n", "QNET> ")
+	ctx, p := newMockProcessor(t, mock)
+	p.limiter = protocol.NewRateLimiter(protocol.RateLimit{MinGap: time.Hour})
+	if _, err := p.Exec(ctx, "#OUTPUT,3,1,50"); err != nil {
+		t.Fatal(err)
+	}
+	// A command for id 3 is now blocked by the minimum gap, but must
+	// not hold up commands for other ids.
+	blocked, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := p.Exec(blocked, "#OUTPUT,3,1,50")
+		errCh <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	start := time.Now()
+	if _, err := p.Exec(ctx, "#OUTPUT,4,1,50"); err != nil {
+		t.Fatal(err)
+	}
+	if took := time.Since(start); took > 500*time.Millisecond {
+		t.Errorf("command for id 4 was delayed by id 3: %v", took)
+	}
+	cancel()
+	if err := <-errCh; !errors.Is(err, context.Canceled) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
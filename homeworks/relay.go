@@ -0,0 +1,232 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package homeworks
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"cloudeng.io/logging/ctxlog"
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/automation/net/streamconn"
+	"github.com/cosnicolaou/lutron/protocol"
+	"gopkg.in/yaml.v3"
+)
+
+// RelayMomentaryConfig represents the configuration for a single contact
+// closure output that is pulsed, eg. a doorbell or a fireplace igniter.
+type RelayMomentaryConfig struct {
+	ID                int           `yaml:"id"`
+	PulseLow          bool          `yaml:"pulse_low"`
+	PulseDuration     time.Duration `yaml:"pulse_duration"`
+	OperationInterval time.Duration `yaml:"operation_interval"`
+}
+
+// RelayLatchedConfig represents the configuration for a single contact
+// closure output that is held open or closed, eg. a pool pump.
+type RelayLatchedConfig struct {
+	ID int `yaml:"id"`
+	// Inverted is true if the relay is off when the output is closed.
+	Inverted bool `yaml:"inverted"`
+}
+
+// Default interval between operations for momentary relays, which are
+// typically operated more frequently than open/close contact closures.
+const defaultRelayOperationInterval = time.Second
+
+// RelayState represents the state of a relay as read back from the
+// processor.
+type RelayState struct {
+	ID    int     `json:"id"`
+	Level float64 `json:"level"`
+	On    bool    `json:"on"`
+}
+
+// RelayMomentary represents a contact closure output that is pulsed.
+type RelayMomentary struct {
+	devices.DeviceBase[RelayMomentaryConfig]
+	processor *QSProcessor
+	pulser    pulser
+}
+
+// RelayLatched represents a contact closure output that is held on or off.
+type RelayLatched struct {
+	devices.DeviceBase[RelayLatchedConfig]
+	processor *QSProcessor
+}
+
+// readRelay reads the level of the contact closure output with the
+// specified ID, any non-zero level is treated as closed.
+func readRelay(ctx context.Context, sess *streamconn.Session, id int, inverted bool) (RelayState, error) {
+	level, err := protocol.GetLevel(ctx, sess, protocol.OutputCommands, id)
+	if err != nil {
+		return RelayState{ID: id}, err
+	}
+	return RelayState{ID: id, Level: level, On: (level > 0) != inverted}, nil
+}
+
+func relayState(ctx context.Context, p *QSProcessor, device string, id int, inverted bool, args devices.OperationArgs) (any, error) {
+	ctx, sess, err := p.session(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	defer sess.Release()
+	grp := slog.Group("lutron", "device", device, "id", id, "op", "state")
+	ctx = ctxlog.WithAttributes(ctx, grp)
+	st, err := readRelay(ctx, sess, id, inverted)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(args.Writer, "state: on %v (level %v)\n", st.On, st.Level)
+	return st, nil
+}
+
+func (r *RelayMomentary) UnmarshalYAML(node *yaml.Node) error {
+	if err := node.Decode(&r.DeviceConfigCustom); err != nil {
+		return err
+	}
+	cfg := &r.DeviceConfigCustom
+	if err := validateID(r.DeviceConfigCommon, "id", cfg.ID); err != nil {
+		return err
+	}
+	return validatePulse(r.DeviceConfigCommon, cfg.PulseDuration, cfg.OperationInterval)
+}
+
+func (r *RelayMomentary) integrationIDs() []integrationID {
+	return []integrationID{{"id", r.DeviceConfigCustom.ID, protocol.OutputCommands}}
+}
+
+func (r *RelayMomentary) SetController(c devices.Controller) {
+	r.processor = c.Implementation().(*QSProcessor)
+}
+
+func (r *RelayMomentary) ControlledBy() devices.Controller {
+	return r.processor
+}
+
+func (r *RelayMomentary) Operations() map[string]devices.Operation {
+	return map[string]devices.Operation{
+		"pulse": r.pulse,
+		"state": r.state,
+	}
+}
+
+func (r *RelayMomentary) OperationsHelp() map[string]string {
+	return map[string]string{
+		"pulse": "pulse the relay",
+		"state": "read back the state of the relay",
+	}
+}
+
+func (r *RelayMomentary) pulse(ctx context.Context, args devices.OperationArgs) (any, error) {
+	cfg := r.DeviceConfigCustom
+	pulse, interval := pulseIntervals(cfg.PulseDuration, cfg.OperationInterval, defaultRelayOperationInterval)
+	return r.pulser.pulse(ctx, r.processor, "relay-momentary", "pulse", cfg.ID, pulse, interval, cfg.PulseLow, args.Writer)
+}
+
+func (r *RelayMomentary) state(ctx context.Context, args devices.OperationArgs) (any, error) {
+	// The relay is on if the output is in its pulsed state.
+	cfg := r.DeviceConfigCustom
+	return relayState(ctx, r.processor, "relay-momentary", cfg.ID, cfg.PulseLow, args)
+}
+
+func (r *RelayLatched) UnmarshalYAML(node *yaml.Node) error {
+	if err := node.Decode(&r.DeviceConfigCustom); err != nil {
+		return err
+	}
+	return validateID(r.DeviceConfigCommon, "id", r.DeviceConfigCustom.ID)
+}
+
+func (r *RelayLatched) integrationIDs() []integrationID {
+	return []integrationID{{"id", r.DeviceConfigCustom.ID, protocol.OutputCommands}}
+}
+
+func (r *RelayLatched) SetController(c devices.Controller) {
+	r.processor = c.Implementation().(*QSProcessor)
+}
+
+func (r *RelayLatched) ControlledBy() devices.Controller {
+	return r.processor
+}
+
+func (r *RelayLatched) Operations() map[string]devices.Operation {
+	return map[string]devices.Operation{
+		"on":     r.on,
+		"off":    r.off,
+		"toggle": r.toggle,
+		"state":  r.state,
+	}
+}
+
+func (r *RelayLatched) OperationsHelp() map[string]string {
+	return map[string]string{
+		"on":     "turn the relay on",
+		"off":    "turn the relay off",
+		"toggle": "toggle the relay based on its current state",
+		"state":  "read back the state of the relay",
+	}
+}
+
+// set sets the relay to the requested state and reads back the result.
+func (r *RelayLatched) set(ctx context.Context, sess *streamconn.Session, on bool, args devices.OperationArgs) (RelayState, error) {
+	cfg := r.DeviceConfigCustom
+	level := 0.0
+	if on != cfg.Inverted {
+		level = 100
+	}
+	if err := protocol.SetLevel(ctx, sess, protocol.OutputCommands, cfg.ID, level, 0, 0); err != nil {
+		return RelayState{ID: cfg.ID}, err
+	}
+	st, err := readRelay(ctx, sess, cfg.ID, cfg.Inverted)
+	if err != nil {
+		return st, err
+	}
+	fmt.Fprintf(args.Writer, "state: on %v (level %v)\n", st.On, st.Level)
+	if st.On != on {
+		return st, fmt.Errorf("relay %v: state is on %v, requested on %v", cfg.ID, st.On, on)
+	}
+	return st, nil
+}
+
+func (r *RelayLatched) runOperation(ctx context.Context, op string, fn func(context.Context, *streamconn.Session) (RelayState, error)) (any, error) {
+	ctx, sess, err := r.processor.session(ctx, r.DeviceConfigCustom.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer sess.Release()
+	grp := slog.Group("lutron", "device", "relay-latched", "id", r.DeviceConfigCustom.ID, "op", op)
+	ctx = ctxlog.WithAttributes(ctx, grp)
+	return fn(ctx, sess)
+}
+
+func (r *RelayLatched) on(ctx context.Context, args devices.OperationArgs) (any, error) {
+	return r.runOperation(ctx, "on", func(ctx context.Context, sess *streamconn.Session) (RelayState, error) {
+		return r.set(ctx, sess, true, args)
+	})
+}
+
+func (r *RelayLatched) off(ctx context.Context, args devices.OperationArgs) (any, error) {
+	return r.runOperation(ctx, "off", func(ctx context.Context, sess *streamconn.Session) (RelayState, error) {
+		return r.set(ctx, sess, false, args)
+	})
+}
+
+func (r *RelayLatched) toggle(ctx context.Context, args devices.OperationArgs) (any, error) {
+	return r.runOperation(ctx, "toggle", func(ctx context.Context, sess *streamconn.Session) (RelayState, error) {
+		cfg := r.DeviceConfigCustom
+		st, err := readRelay(ctx, sess, cfg.ID, cfg.Inverted)
+		if err != nil {
+			return st, err
+		}
+		return r.set(ctx, sess, !st.On, args)
+	})
+}
+
+func (r *RelayLatched) state(ctx context.Context, args devices.OperationArgs) (any, error) {
+	cfg := r.DeviceConfigCustom
+	return relayState(ctx, r.processor, "relay-latched", cfg.ID, cfg.Inverted, args)
+}
@@ -0,0 +1,99 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package homeworks
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/lutron/internal/testutil"
+)
+
+func TestRelayMomentary(t *testing.T) {
+	mock := testutil.NewMockTransport(testing.Verbose())
+	mock.SetResponse("#OUTPUT,7,1,0\r\n", "QNET> ")
+	mock.SetResponse("#OUTPUT,7,1,1\r\n", "QNET> ")
+	mock.SetResponse("?OUTPUT,7,1\r\n", "~OUTPUT,7,1,0.00\r\nQNET> ")
+	ctx, p := newMockProcessor(t, mock)
+	sent := recordSent(t, p)
+	r := &RelayMomentary{processor: p}
+	r.DeviceConfigCustom = RelayMomentaryConfig{
+		ID:            7,
+		PulseLow:      true,
+		PulseDuration: 10 * time.Millisecond,
+	}
+	out := &bytes.Buffer{}
+	res, err := r.pulse(ctx, devices.OperationArgs{Writer: out})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pr := res.(PulseResult); pr.ID != 7 || pr.Canceled {
+		t.Errorf("unexpected result: %+v", pr)
+	}
+	res, err = r.state(ctx, devices.OperationArgs{Writer: out})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A pulse low relay is on when the output is open.
+	if got, want := res.(RelayState), (RelayState{ID: 7, Level: 0, On: true}); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if got, want := sent(), []string{
+		"admin\r\n",
+		"#OUTPUT,7,1,0\r\n", "#OUTPUT,7,1,1\r\n",
+		"?OUTPUT,7,1\r\n",
+	}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRelayLatched(t *testing.T) {
+	mock := testutil.NewMockTransport(testing.Verbose())
+	mock.SetResponse("#OUTPUT,8,1,100\r\n", "QNET> ")
+	mock.SetResponse("#OUTPUT,8,1,0\r\n", "QNET> ")
+	mock.SetResponse("?OUTPUT,8,1\r\n", "~OUTPUT,8,1,100.00\r\nQNET> ")
+	ctx, p := newMockProcessor(t, mock)
+	sent := recordSent(t, p)
+	r := &RelayLatched{processor: p}
+	r.DeviceConfigCustom = RelayLatchedConfig{ID: 8}
+	out := &bytes.Buffer{}
+
+	res, err := r.on(ctx, devices.OperationArgs{Writer: out})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := res.(RelayState), (RelayState{ID: 8, Level: 100, On: true}); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	// The mock always reports the relay as closed, so toggling it off
+	// must be detected as having failed.
+	_, err = r.toggle(ctx, devices.OperationArgs{Writer: out})
+	if err == nil || !strings.Contains(err.Error(), "state is on true, requested on false") {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// An inverted relay is off when the output is closed.
+	r.DeviceConfigCustom.Inverted = true
+	res, err = r.off(ctx, devices.OperationArgs{Writer: out})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := res.(RelayState), (RelayState{ID: 8, Level: 100, On: false}); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if got, want := sent(), []string{
+		"admin\r\n",
+		"#OUTPUT,8,1,100\r\n", "?OUTPUT,8,1\r\n",
+		"?OUTPUT,8,1\r\n", "#OUTPUT,8,1,0\r\n", "?OUTPUT,8,1\r\n",
+		"#OUTPUT,8,1,100\r\n", "?OUTPUT,8,1\r\n",
+	}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,237 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package homeworks
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+
+	"cloudeng.io/logging/ctxlog"
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/lutron/protocol"
+	"gopkg.in/yaml.v3"
+)
+
+// levelTarget is implemented by devices whose level can be set via
+// an OUTPUT or SHADEGRP command.
+type levelTarget interface {
+	levelTarget() (protocol.CommandGroup, int)
+}
+
+// SceneMember represents a single device within a scene and the level
+// it is to be set to.
+type SceneMember struct {
+	Device string        `yaml:"device"`
+	Level  float64       `yaml:"level"`
+	Fade   time.Duration `yaml:"fade"`
+	Delay  time.Duration `yaml:"delay"`
+}
+
+// SceneConfig represents the configuration of a scene, ie. a set of
+// devices that are to be set to specific levels in a single operation.
+type SceneConfig struct {
+	Members     []SceneMember `yaml:"members"`
+	Concurrency int           `yaml:"concurrency"`  // defaults to 1.
+	Verify      bool          `yaml:"verify"`       // query the final levels.
+	VerifyDelay time.Duration `yaml:"verify_delay"` // defaults to the longest fade plus delay.
+	Tolerance   float64       `yaml:"tolerance"`    // allowed difference when verifying, defaults to 1.
+}
+
+// Scene represents a scene spanning multiple devices.
+type Scene struct {
+	devices.DeviceBase[SceneConfig]
+	processor *QSProcessor
+}
+
+// SceneMemberResult represents the outcome of setting a single member
+// of a scene.
+type SceneMemberResult struct {
+	Device   string   `json:"device"`
+	Level    float64  `json:"level"`
+	Verified *float64 `json:"verified,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+func (sc *Scene) UnmarshalYAML(node *yaml.Node) error {
+	if err := node.Decode(&sc.DeviceConfigCustom); err != nil {
+		return err
+	}
+	cfg := &sc.DeviceConfigCustom
+	if len(cfg.Members) == 0 {
+		return configError(sc.DeviceConfigCommon, "members", "scene must have at least one member")
+	}
+	for i, m := range cfg.Members {
+		field := fmt.Sprintf("members[%v]", i)
+		if len(m.Device) == 0 {
+			return configError(sc.DeviceConfigCommon, field+".device", "device must be specified")
+		}
+		if err := validateLevel(sc.DeviceConfigCommon, field+".level", m.Level); err != nil {
+			return err
+		}
+		if m.Fade < 0 || m.Delay < 0 {
+			return configError(sc.DeviceConfigCommon, field, "fade and delay must not be negative")
+		}
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	if cfg.Tolerance <= 0 {
+		cfg.Tolerance = 1
+	}
+	return nil
+}
+
+func (sc *Scene) SetController(c devices.Controller) {
+	sc.processor = c.Implementation().(*QSProcessor)
+}
+
+func (sc *Scene) ControlledBy() devices.Controller {
+	return sc.processor
+}
+
+func (sc *Scene) Operations() map[string]devices.Operation {
+	return map[string]devices.Operation{
+		"run": sc.run,
+	}
+}
+
+func (sc *Scene) OperationsHelp() map[string]string {
+	return map[string]string{
+		"run": "set all of the devices in the scene to their configured levels",
+	}
+}
+
+func (sc *Scene) resolve(name string) (protocol.CommandGroup, int, error) {
+	dev, ok := sc.processor.System().Devices[name]
+	if !ok {
+		return 0, 0, fmt.Errorf("unknown device: %q", name)
+	}
+	lt, ok := dev.(levelTarget)
+	if !ok {
+		return 0, 0, fmt.Errorf("device %q does not support setting a level", name)
+	}
+	cg, id := lt.levelTarget()
+	return cg, id, nil
+}
+
+func (sc *Scene) setMember(ctx context.Context, m SceneMember) error {
+	cg, id, err := sc.resolve(m.Device)
+	if err != nil {
+		return err
+	}
+	ctx, sess, err := sc.processor.session(ctx, id)
+	if err != nil {
+		return err
+	}
+	defer sess.Release()
+	grp := slog.Group("lutron", "device", "scene", "member", m.Device, "id", id, "op", "set")
+	ctx = ctxlog.WithAttributes(ctx, grp)
+	return protocol.SetLevel(ctx, sess, cg, id, m.Level, m.Fade, m.Delay)
+}
+
+func (sc *Scene) verifyMember(ctx context.Context, m SceneMember) (float64, error) {
+	cg, id, err := sc.resolve(m.Device)
+	if err != nil {
+		return 0, err
+	}
+	ctx, sess, err := sc.processor.session(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	defer sess.Release()
+	level, err := protocol.GetLevel(ctx, sess, cg, id)
+	if err != nil {
+		return 0, err
+	}
+	if math.Abs(level-m.Level) > sc.DeviceConfigCustom.Tolerance {
+		return level, fmt.Errorf("level %v differs from requested level %v", level, m.Level)
+	}
+	return level, nil
+}
+
+// forEachMember calls fn for each member of the scene with at most
+// Concurrency calls in flight. Note that all commands share a single
+// connection to the processor and hence are serialized on that connection,
+// the concurrency allows for overlapping session acquisition and
+// response processing.
+func (sc *Scene) forEachMember(ctx context.Context, fn func(context.Context, int, SceneMember)) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, sc.DeviceConfigCustom.Concurrency)
+	for i, m := range sc.DeviceConfigCustom.Members {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(ctx, i, m)
+		}()
+	}
+	wg.Wait()
+}
+
+func (sc *Scene) verifyDelay() time.Duration {
+	if d := sc.DeviceConfigCustom.VerifyDelay; d > 0 {
+		return d
+	}
+	var d time.Duration
+	for _, m := range sc.DeviceConfigCustom.Members {
+		d = max(d, m.Fade+m.Delay)
+	}
+	return d
+}
+
+func (sc *Scene) run(ctx context.Context, args devices.OperationArgs) (any, error) {
+	cfg := sc.DeviceConfigCustom
+	results := make([]SceneMemberResult, len(cfg.Members))
+	for i, m := range cfg.Members {
+		results[i] = SceneMemberResult{Device: m.Device, Level: m.Level, Error: "not attempted"}
+	}
+	sc.forEachMember(ctx, func(ctx context.Context, i int, m SceneMember) {
+		results[i].Error = ""
+		if err := sc.setMember(ctx, m); err != nil {
+			results[i].Error = err.Error()
+		}
+	})
+	if cfg.Verify {
+		select {
+		case <-ctx.Done():
+			return results, ctx.Err()
+		case <-time.After(sc.verifyDelay()):
+		}
+		sc.forEachMember(ctx, func(ctx context.Context, i int, m SceneMember) {
+			if len(results[i].Error) > 0 {
+				return
+			}
+			level, err := sc.verifyMember(ctx, m)
+			if err != nil {
+				results[i].Error = err.Error()
+				return
+			}
+			results[i].Verified = &level
+		})
+	}
+	failed := 0
+	for _, r := range results {
+		if len(r.Error) > 0 {
+			failed++
+			fmt.Fprintf(args.Writer, "%v: %v: failed: %v\n", sc.Name, r.Device, r.Error)
+			continue
+		}
+		fmt.Fprintf(args.Writer, "%v: %v: %v\n", sc.Name, r.Device, r.Level)
+	}
+	if failed > 0 {
+		return results, fmt.Errorf("scene %v: %v of %v members failed", sc.Name, failed, len(results))
+	}
+	return results, nil
+}
@@ -0,0 +1,192 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package homeworks
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/lutron/internal/testutil"
+	"gopkg.in/yaml.v3"
+)
+
+const sceneSpec = `
+controllers:
+  - name: home
+    type: homeworks-qs
+    keep_alive: 1m
+
+devices:
+  - name: living room
+    type: shadegrp
+    controller: home
+    id: 1
+  - name: evening
+    type: scene
+    controller: home
+    verify: true
+    members:
+      - device: living room
+        level: 25
+        fade: 2s
+`
+
+func createSceneSystem(ctx context.Context, t *testing.T, spec string) (map[string]devices.Controller, map[string]devices.Device, error) {
+	t.Helper()
+	var cfg struct {
+		Controllers []devices.ControllerConfig `yaml:"controllers"`
+		Devices     []devices.DeviceConfig     `yaml:"devices"`
+	}
+	if err := yaml.Unmarshal([]byte(spec), &cfg); err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+	return devices.CreateSystem(ctx,
+		cfg.Controllers,
+		cfg.Devices,
+		devices.WithDevices(SupportedDevices()),
+		devices.WithControllers(SupportedControllers()))
+}
+
+func TestSceneConfig(t *testing.T) {
+	ctx := context.Background()
+	_, devs, err := createSceneSystem(ctx, t, sceneSpec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scene := devs["evening"]
+	if got, want := scene.CustomConfig().(SceneConfig), (SceneConfig{
+		Members: []SceneMember{
+			{Device: "living room", Level: 25, Fade: 2 * time.Second},
+		},
+		Concurrency: 1,
+		Verify:      true,
+		Tolerance:   1,
+	}); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if _, ok := scene.Operations()["run"]; !ok {
+		t.Errorf("missing run operation")
+	}
+
+	bad := strings.Replace(sceneSpec, "level: 25", "level: 125", 1)
+	if _, _, err := createSceneSystem(ctx, t, bad); err == nil || !strings.Contains(err.Error(), "level must be in the range") {
+		t.Errorf("unexpected or missing error: %v", err)
+	}
+}
+
+func TestSceneRun(t *testing.T) {
+	mock := testutil.NewMockTransport(testing.Verbose())
+	mock.SetResponse("#OUTPUT,3,1,50\r\n", "QNET> ")
+	mock.SetResponse("#SHADEGRP,4,1,25,1\r\n", "QNET> ")
+	mock.SetResponse("#OUTPUT,6,1,10\r\n", "~ERROR,2\r\nQNET> ")
+	mock.SetResponse("?OUTPUT,3,1\r\n", "~OUTPUT,3,1,49.50\r\nQNET> ")
+	mock.SetResponse("?SHADEGRP,4,1\r\n", "~SHADEGRP,4,1,40.00\r\nQNET> ")
+	sc := &Scene{}
+	sc.Name = "evening"
+	sc.DeviceConfigCustom = SceneConfig{
+		Members: []SceneMember{
+			{Device: "kitchen", Level: 50},
+			{Device: "living room", Level: 25, Fade: time.Second},
+			{Device: "garage", Level: 100},
+			{Device: "bedroom", Level: 10},
+		},
+		Concurrency: 1,
+		Verify:      true,
+		VerifyDelay: time.Millisecond,
+		Tolerance:   1,
+	}
+	ctx, sent := newMockDevice(t, mock, sc)
+	kitchen, group, bedroom := &HWShade{}, &HWShadeGroup{}, &HWShade{}
+	kitchen.DeviceConfigCustom.ID, group.DeviceConfigCustom.ID, bedroom.DeviceConfigCustom.ID = 3, 4, 6
+	members := map[string]devices.Device{"kitchen": kitchen, "living room": group, "bedroom": bedroom}
+	for _, d := range members {
+		d.SetController(sc.processor)
+	}
+	sc.processor.SetSystem(devices.System{Devices: members})
+	out := &bytes.Buffer{}
+	res, err := sc.run(ctx, devices.OperationArgs{Writer: out})
+	if err == nil || !strings.Contains(err.Error(), "3 of 4 members failed") {
+		t.Errorf("unexpected or missing error: %v", err)
+	}
+	results := res.([]SceneMemberResult)
+	// Partial failures are reported per member and do not prevent the
+	// remaining members from being set or verified.
+	for i, tc := range []struct {
+		verified float64
+		err      string
+	}{
+		{49.5, ""},
+		{0, "level 40 differs from requested level 25"},
+		{0, `unknown device: "garage"`},
+		{0, "object does not exist"},
+	} {
+		r := results[i]
+		if len(tc.err) == 0 {
+			if len(r.Error) > 0 || r.Verified == nil || *r.Verified != tc.verified {
+				t.Errorf("%v: unexpected result: %+v", r.Device, r)
+			}
+			continue
+		}
+		if !strings.Contains(r.Error, tc.err) || r.Verified != nil {
+			t.Errorf("%v: got %+v, want error containing %q", r.Device, r, tc.err)
+		}
+	}
+	// Members that failed to be set are not verified.
+	if got, want := sent(), []string{
+		"admin\r\n",
+		"#OUTPUT,3,1,50\r\n", "#SHADEGRP,4,1,25,1\r\n", "#OUTPUT,6,1,10\r\n",
+		"?OUTPUT,3,1\r\n", "?SHADEGRP,4,1\r\n",
+	}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := out.String(); !strings.Contains(got, "evening: kitchen: 50\n") || !strings.Contains(got, "evening: garage: failed") {
+		t.Errorf("unexpected output: %v", got)
+	}
+
+	// Verification within the tolerance succeeds.
+	sc.DeviceConfigCustom.Members = sc.DeviceConfigCustom.Members[:1]
+	if _, err := sc.run(ctx, devices.OperationArgs{Writer: out}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	sc.DeviceConfigCustom.Tolerance = 0.1
+	if _, err := sc.run(ctx, devices.OperationArgs{Writer: out}); err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func TestSceneConcurrency(t *testing.T) {
+	sc := &Scene{}
+	sc.DeviceConfigCustom.Concurrency = 2
+	for range 6 {
+		sc.DeviceConfigCustom.Members = append(sc.DeviceConfigCustom.Members, SceneMember{})
+	}
+	var mu sync.Mutex
+	inflight, peak, calls := 0, 0, 0
+	sc.forEachMember(context.Background(), func(context.Context, int, SceneMember) {
+		mu.Lock()
+		inflight++
+		calls++
+		peak = max(peak, inflight)
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		inflight--
+		mu.Unlock()
+	})
+	if got, want := calls, 6; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := peak, 2; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+}
@@ -0,0 +1,149 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package homeworks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+
+	"cloudeng.io/logging/ctxlog"
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/automation/net/streamconn"
+	"github.com/cosnicolaou/lutron/protocol"
+)
+
+// ShadeGroupResult is returned by shade group commands to indicate whether
+// the command was sent to the group or fanned out to its members.
+type ShadeGroupResult struct {
+	FanOut   bool   `json:"fan_out"`
+	GroupErr string `json:"group_error,omitempty"`
+}
+
+// ShadeMemberLevel represents the level of a single member of a shade group.
+type ShadeMemberLevel struct {
+	ID    int     `json:"id"`
+	Level float64 `json:"level"`
+	Error string  `json:"error,omitempty"`
+}
+
+// ShadeGroupVerification represents the outcome of checking that all of
+// the members of a shade group have reached a target level.
+type ShadeGroupVerification struct {
+	Target  float64            `json:"target"`
+	Members []ShadeMemberLevel `json:"members"`
+	Missed  []int              `json:"missed,omitempty"`
+}
+
+// defaultShadeTolerance is the default allowed difference between a
+// member's level and the target level.
+const defaultShadeTolerance = 1.0
+
+func actionCommand(id int, action []byte) []byte {
+	pars := strconv.AppendInt(make([]byte, 0, 16), int64(id), 10)
+	pars = append(pars, ',')
+	return append(pars, action...)
+}
+
+// runGroupCommand sends the supplied action to the shade group and, if that
+// fails and fan out is enabled, sends it to each member of the group using
+// OUTPUT commands.
+func (sg *HWShadeGroup) runGroupCommand(ctx context.Context, action []byte, op string, args devices.OperationArgs) (any, error) {
+	cfg := sg.DeviceConfigCustom
+	ctx, sess, err := sg.processor.session(ctx, cfg.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer sess.Release()
+	grp := slog.Group("lutron", "device", "shadegrp", "id", cfg.ID, "op", op)
+	ctx = ctxlog.WithAttributes(ctx, grp)
+	err = protocol.NewCommand(protocol.ShadeGroupCommands, true, actionCommand(cfg.ID, action)).Invoke(ctx, sess)
+	if err == nil || !cfg.FanOut {
+		return nil, err
+	}
+	ctxlog.Info(ctx, "shade group command failed, fanning out to members", "members", cfg.Members, "err", err)
+	fmt.Fprintf(args.Writer, "%v: group command failed: %v, fanning out to members %v\n", op, err, cfg.Members)
+	result := ShadeGroupResult{FanOut: true, GroupErr: err.Error()}
+	var errs []error
+	for _, id := range cfg.Members {
+		if err := protocol.NewCommand(protocol.OutputCommands, true, actionCommand(id, action)).Invoke(ctx, sess); err != nil {
+			errs = append(errs, fmt.Errorf("member %v: %w", id, err))
+		}
+	}
+	return result, errors.Join(errs...)
+}
+
+func (sg *HWShadeGroup) memberLevels(ctx context.Context, sess *streamconn.Session) []ShadeMemberLevel {
+	levels := make([]ShadeMemberLevel, len(sg.DeviceConfigCustom.Members))
+	for i, id := range sg.DeviceConfigCustom.Members {
+		levels[i].ID = id
+		level, err := protocol.GetLevel(ctx, sess, protocol.OutputCommands, id)
+		if err != nil {
+			levels[i].Error = err.Error()
+			continue
+		}
+		levels[i].Level = level
+	}
+	return levels
+}
+
+func (sg *HWShadeGroup) queryMembers(ctx context.Context, op string) ([]ShadeMemberLevel, error) {
+	ctx, sess, err := sg.processor.session(ctx, sg.DeviceConfigCustom.Members...)
+	if err != nil {
+		return nil, err
+	}
+	defer sess.Release()
+	grp := slog.Group("lutron", "device", "shadegrp", "id", sg.DeviceConfigCustom.ID, "op", op)
+	ctx = ctxlog.WithAttributes(ctx, grp)
+	return sg.memberLevels(ctx, sess), nil
+}
+
+func (sg *HWShadeGroup) members(ctx context.Context, args devices.OperationArgs) (any, error) {
+	levels, err := sg.queryMembers(ctx, "members")
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range levels {
+		if len(l.Error) > 0 {
+			fmt.Fprintf(args.Writer, "%v: failed: %v\n", l.ID, l.Error)
+			continue
+		}
+		fmt.Fprintf(args.Writer, "%v: %v\n", l.ID, l.Level)
+	}
+	return levels, nil
+}
+
+// verify checks that every member of the group is within the configured
+// tolerance of the target level, members that could not be queried are
+// treated as having missed the target.
+func (sg *HWShadeGroup) verify(ctx context.Context, args devices.OperationArgs) (any, error) {
+	target, err := parseShadeLevel(args.Args)
+	if err != nil {
+		return nil, err
+	}
+	levels, err := sg.queryMembers(ctx, "verify")
+	if err != nil {
+		return nil, err
+	}
+	tolerance := sg.DeviceConfigCustom.Tolerance
+	if tolerance == 0 {
+		tolerance = defaultShadeTolerance
+	}
+	result := ShadeGroupVerification{Target: float64(target), Members: levels}
+	for _, l := range levels {
+		if len(l.Error) > 0 || math.Abs(l.Level-result.Target) > tolerance {
+			result.Missed = append(result.Missed, l.ID)
+		}
+	}
+	if len(result.Missed) > 0 {
+		fmt.Fprintf(args.Writer, "members %v did not reach %v\n", result.Missed, target)
+		return result, fmt.Errorf("shade group %v: members %v did not reach %v", sg.Name, result.Missed, target)
+	}
+	fmt.Fprintf(args.Writer, "all members at %v\n", target)
+	return result, nil
+}
@@ -0,0 +1,227 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package homeworks
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/lutron/protocol"
+)
+
+const (
+	// defaultShadePoll is the interval at which a shade's level is queried
+	// whilst waiting for it to arrive.
+	defaultShadePoll = time.Second
+	// maxShadeWait is the longest time to wait for a shade whose travel
+	// time is unknown.
+	maxShadeWait = 2 * time.Minute
+	// minLearnDistance is the smallest movement, in percent, that is used
+	// to learn a shade's travel time, shorter movements are dominated by
+	// start up and polling delays.
+	minLearnDistance = 10.0
+)
+
+// ShadeMotion represents a shade's movement to a target level.
+type ShadeMotion struct {
+	From      float64       `json:"from"`
+	Target    float64       `json:"target"`
+	Level     float64       `json:"level"`
+	Arrived   bool          `json:"arrived"`
+	Estimated time.Duration `json:"estimated"`
+	Elapsed   time.Duration `json:"elapsed"`
+}
+
+// travelEstimator estimates a shade's travel time from its configuration
+// or from the travel times observed for previous movements.
+type travelEstimator struct {
+	mu      sync.Mutex
+	poll    time.Duration
+	learned time.Duration // the learned time for a full 0..100 movement.
+}
+
+func newTravelEstimator() *travelEstimator {
+	return &travelEstimator{poll: defaultShadePoll}
+}
+
+// fullTravel returns the configured travel time if non-zero, or the
+// learned travel time otherwise. A nil travelEstimator has no
+// learned travel time.
+func (te *travelEstimator) fullTravel(configured time.Duration) time.Duration {
+	if configured > 0 || te == nil {
+		return configured
+	}
+	te.mu.Lock()
+	defer te.mu.Unlock()
+	return te.learned
+}
+
+func (te *travelEstimator) pollInterval() time.Duration {
+	if te == nil || te.poll == 0 {
+		return defaultShadePoll
+	}
+	return te.poll
+}
+
+// learn updates the learned travel time using an exponentially weighted
+// average of the observed times for a full movement.
+func (te *travelEstimator) learn(distance float64, elapsed time.Duration) {
+	if te == nil || distance < minLearnDistance || elapsed <= 0 {
+		return
+	}
+	full := time.Duration(float64(elapsed) * 100 / distance)
+	te.mu.Lock()
+	defer te.mu.Unlock()
+	if te.learned == 0 {
+		te.learned = full
+		return
+	}
+	te.learned = (3*te.learned + full) / 4
+}
+
+// parseWait removes a trailing 'wait' argument, if present, and returns
+// the remaining arguments and whether to wait for the shade to arrive.
+func parseWait(args []string, wait bool) ([]string, bool) {
+	if n := len(args); n > 0 && args[n-1] == "wait" {
+		return args[:n-1], true
+	}
+	return args, wait
+}
+
+func (sb hwShadeBase) tolerance() float64 {
+	if t := sb.DeviceConfigCustom.Tolerance; t > 0 {
+		return t
+	}
+	return defaultShadeTolerance
+}
+
+func (sb hwShadeBase) level(ctx context.Context, cg protocol.CommandGroup) (float64, error) {
+	ctx, sess, err := sb.processor.session(ctx, sb.DeviceConfigCustom.ID)
+	if err != nil {
+		return 0, err
+	}
+	defer sess.Release()
+	return protocol.GetLevel(ctx, sess, cg, sb.DeviceConfigCustom.ID)
+}
+
+// runAndWait runs the supplied command and, if wait is true, waits for
+// the shade to arrive at the target level. It returns a ShadeMotion
+// if waiting, or the result of the command otherwise.
+func (sb hwShadeBase) runAndWait(ctx context.Context, cg protocol.CommandGroup, target float64, wait bool, w io.Writer, run func(context.Context) (any, error)) (any, error) {
+	if !wait {
+		return run(ctx)
+	}
+	from, err := sb.level(ctx, cg)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	if _, err := run(ctx); err != nil {
+		return nil, err
+	}
+	motion, err := sb.waitForArrival(ctx, cg, from, target, start)
+	if motion.Arrived {
+		fmt.Fprintf(w, "arrived at %v after %v (estimated %v)\n", motion.Level, motion.Elapsed.Round(time.Millisecond), motion.Estimated.Round(time.Millisecond))
+	}
+	return motion, err
+}
+
+// waitForArrival waits for the shade to reach the target level. The level
+// is queried once the estimated travel time has elapsed, and then at the
+// poll interval, or whenever monitoring reports a change in the shade's
+// level. The arrival time is taken from the monitoring event if it
+// prompted the query that confirmed the arrival.
+func (sb hwShadeBase) waitForArrival(ctx context.Context, cg protocol.CommandGroup, from, target float64, start time.Time) (ShadeMotion, error) {
+	id := sb.DeviceConfigCustom.ID
+	distance := math.Abs(target - from)
+	motion := ShadeMotion{
+		From:      from,
+		Target:    target,
+		Level:     from,
+		Estimated: time.Duration(float64(sb.travel.fullTravel(sb.DeviceConfigCustom.TravelTime)) * distance / 100),
+	}
+	maxWait := maxShadeWait
+	if motion.Estimated > 0 {
+		maxWait = 2*motion.Estimated + 2*sb.travel.pollInterval()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	updates := sb.processor.State().Watch(ctx, 4, StateKey{ID: id, Component: int(protocol.OutputLevel)})
+	next := start.Add(max(motion.Estimated, sb.travel.pollInterval()))
+	for {
+		var observed time.Time
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			motion.Elapsed = time.Since(start)
+			return motion, ctx.Err()
+		case st := <-updates:
+			observed = st.Updated
+		case <-timer.C:
+		}
+		timer.Stop()
+		level, err := sb.level(ctx, cg)
+		now := time.Now()
+		if observed.IsZero() {
+			observed = now
+		}
+		if err == nil {
+			motion.Level = level
+		}
+		if err == nil && math.Abs(level-target) <= sb.tolerance() {
+			motion.Arrived = true
+			motion.Elapsed = observed.Sub(start)
+			sb.travel.learn(distance, motion.Elapsed)
+			return motion, nil
+		}
+		if now.Sub(start) >= maxWait {
+			motion.Elapsed = now.Sub(start)
+			return motion, fmt.Errorf("shade %v did not reach %v within %v, level is %v", id, target, maxWait, motion.Level)
+		}
+		next = now.Add(sb.travel.pollInterval())
+	}
+}
+
+// shadeAction returns the SHADEGRP/OUTPUT action parameters and the
+// target level for the supplied operation.
+func shadeAction(op string, args []string) ([]byte, float64, error) {
+	switch op {
+	case "raise":
+		return []byte{'2'}, 100, nil
+	case "lower":
+		return []byte{'3'}, 0, nil
+	case "stop":
+		return []byte{'4'}, 0, nil
+	}
+	level, err := parseShadeLevel(args)
+	if err != nil {
+		return nil, 0, err
+	}
+	return strconv.AppendInt([]byte{'1', ','}, int64(level), 10), float64(level), nil
+}
+
+// move runs the raise, lower, stop or set operation, using run to send
+// the command, and waits for the shade to arrive at its target if a
+// trailing 'wait' argument is supplied or waiting is configured.
+func (sb hwShadeBase) move(ctx context.Context, cg protocol.CommandGroup, op string, args devices.OperationArgs, run func(context.Context, []byte) (any, error)) (any, error) {
+	pars, wait := parseWait(args.Args, sb.DeviceConfigCustom.Wait)
+	action, target, err := shadeAction(op, pars)
+	if err != nil {
+		return nil, err
+	}
+	if op == "stop" {
+		wait = false
+	}
+	return sb.runAndWait(ctx, cg, target, wait, args.Writer, func(ctx context.Context) (any, error) {
+		return run(ctx, action)
+	})
+}
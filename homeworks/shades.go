@@ -8,20 +8,24 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"maps"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"cloudeng.io/logging/ctxlog"
 	"github.com/cosnicolaou/automation/devices"
 	"github.com/cosnicolaou/lutron/protocol"
+	"gopkg.in/yaml.v3"
 )
 
 func parseShadeLevel(pars []string) (int, error) {
-	level := 50
 	if len(pars) != 1 {
 		return 0, fmt.Errorf("must specify a level")
 	}
 	l, err := strconv.Atoi(pars[0])
-	if err != nil || (level < 0 || level > 100) {
+	if err != nil || (l < 0 || l > 100) {
 		return 0, fmt.Errorf("level must be in the range 0..100")
 	}
 	return l, nil
@@ -29,11 +33,73 @@ func parseShadeLevel(pars []string) (int, error) {
 
 type HWShadeConfig struct {
 	ID int `yaml:"id"`
+	// TravelTime is the time taken for the shade to move from fully
+	// closed to fully open, if not specified it is learned from
+	// previous movements.
+	TravelTime time.Duration `yaml:"travel_time"`
+	// Wait enables waiting for the shade to arrive at its target level
+	// for all operations, rather than only when requested.
+	Wait bool `yaml:"wait"`
+	// Presets are named levels, eg. privacy: 25, that may be used
+	// with the preset operation.
+	Presets map[string]int `yaml:"presets"`
+	// Tolerance is the allowed difference between the shade's level and
+	// the target level when waiting for it to arrive or when verifying
+	// a group's members, it defaults to 1.
+	Tolerance float64 `yaml:"tolerance"`
+	// Glare configures the shade, or group, for glare control.
+	Glare *GlareConfig `yaml:"glare"`
+
+	// The following are only used for shade groups.
+
+	// Members are the integration IDs of the shades in the group.
+	Members []int `yaml:"members"`
+	// FanOut enables sending OUTPUT commands to each member if
+	// the SHADEGRP command for the group fails.
+	FanOut bool `yaml:"fan_out"`
 }
 
 type hwShadeBase struct {
 	devices.DeviceBase[HWShadeConfig]
 	processor *QSProcessor
+	travel    *travelEstimator
+	glare     *glareState
+}
+
+func (sb *hwShadeBase) UnmarshalYAML(node *yaml.Node) error {
+	if err := node.Decode(&sb.DeviceConfigCustom); err != nil {
+		return err
+	}
+	cfg := &sb.DeviceConfigCustom
+	if err := validateID(sb.DeviceConfigCommon, "id", cfg.ID); err != nil {
+		return err
+	}
+	for i, id := range cfg.Members {
+		if err := validateID(sb.DeviceConfigCommon, fmt.Sprintf("members[%v]", i), id); err != nil {
+			return err
+		}
+	}
+	if cfg.FanOut && len(cfg.Members) == 0 {
+		return configError(sb.DeviceConfigCommon, "fan_out", "requires members to be specified")
+	}
+	for _, name := range slices.Sorted(maps.Keys(cfg.Presets)) {
+		if len(name) == 0 || name == "wait" {
+			return configError(sb.DeviceConfigCommon, "presets", "invalid preset name: %q", name)
+		}
+		if err := validateLevel(sb.DeviceConfigCommon, "presets."+name, float64(cfg.Presets[name])); err != nil {
+			return err
+		}
+	}
+	if cfg.TravelTime < 0 {
+		return configError(sb.DeviceConfigCommon, "travel_time", "must not be negative, not %v", cfg.TravelTime)
+	}
+	if cfg.Tolerance < 0 {
+		return configError(sb.DeviceConfigCommon, "tolerance", "must not be negative, not %v", cfg.Tolerance)
+	}
+	if cfg.Glare != nil {
+		return cfg.Glare.validate(sb.DeviceConfigCommon)
+	}
+	return nil
 }
 
 func (sb *hwShadeBase) SetController(c devices.Controller) {
@@ -45,25 +111,70 @@ func (sb *hwShadeBase) ControlledBy() devices.Controller {
 }
 
 func (sb hwShadeBase) OperationsHelp() map[string]string {
-	return map[string]string{
-		"raise": "raise the shade",
-		"lower": "lower the shade",
+	help := map[string]string{
+		"raise": "raise the shade, append 'wait' to wait for it to arrive",
+		"lower": "lower the shade, append 'wait' to wait for it to arrive",
 		"stop":  "stop the shade",
-		"set":   "set the shade level",
+		"set":   "set the shade level, append 'wait' to wait for it to arrive",
+	}
+	if presets := sb.DeviceConfigCustom.Presets; len(presets) > 0 {
+		names := slices.Sorted(maps.Keys(presets))
+		for i, name := range names {
+			names[i] = fmt.Sprintf("%v (%v)", name, presets[name])
+		}
+		help["preset"] = "set the shade to a preset level, one of: " + strings.Join(names, ", ") + ", append 'wait' to wait for it to arrive"
+	}
+	if sb.DeviceConfigCustom.Glare != nil {
+		help["glare"] = "set the shade to keep direct sun out of the room, append 'force' to ignore hysteresis and 'wait' to wait for it to arrive"
 	}
+	return help
 }
 
 func (sb hwShadeBase) operations(raise, lower, stop, set devices.Operation) map[string]devices.Operation {
-	return map[string]devices.Operation{
+	ops := map[string]devices.Operation{
 		"raise": raise,
 		"lower": lower,
 		"stop":  stop,
 		"set":   set,
 	}
+	if len(sb.DeviceConfigCustom.Presets) > 0 {
+		ops["preset"] = func(ctx context.Context, args devices.OperationArgs) (any, error) {
+			pargs, err := sb.presetArgs(args)
+			if err != nil {
+				return nil, err
+			}
+			return set(ctx, pargs)
+		}
+	}
+	if sb.DeviceConfigCustom.Glare != nil {
+		ops["glare"] = func(ctx context.Context, args devices.OperationArgs) (any, error) {
+			return sb.adjustGlare(ctx, time.Now(), args, set)
+		}
+	}
+	return ops
+}
+
+// presetArgs returns the arguments for the set operation that correspond
+// to the preset named by the first argument.
+func (sb hwShadeBase) presetArgs(args devices.OperationArgs) (devices.OperationArgs, error) {
+	if len(args.Args) == 0 {
+		return args, fmt.Errorf("must specify a preset")
+	}
+	level, ok := sb.DeviceConfigCustom.Presets[args.Args[0]]
+	if !ok {
+		return args, fmt.Errorf("unknown preset: %q", args.Args[0])
+	}
+	pargs := args
+	pargs.Args = append([]string{strconv.Itoa(level)}, args.Args[1:]...)
+	return pargs, nil
+}
+
+func (sb hwShadeBase) glareEnabled() bool {
+	return sb.DeviceConfigCustom.Glare != nil
 }
 
 func (sb hwShadeBase) runShadeCommand(ctx context.Context, cg protocol.CommandGroup, pars []byte, op string) (any, error) {
-	ctx, sess, err := sb.processor.session(ctx)
+	ctx, sess, err := sb.processor.session(ctx, sb.DeviceConfigCustom.ID)
 	if err != nil {
 		return nil, err
 	}
@@ -74,78 +185,113 @@ func (sb hwShadeBase) runShadeCommand(ctx context.Context, cg protocol.CommandGr
 	return nil, err
 }
 
-func (sb hwShadeBase) raiseShade(ctx context.Context, cg protocol.CommandGroup) (any, error) {
-	pars := append([]byte(strconv.Itoa(sb.DeviceConfigCustom.ID)), ',', '2')
-	return sb.runShadeCommand(ctx, cg, pars, "raise")
+// HWShadeGroupConfig represents the configuration for a group of shades
+// as configured as a single group.
+type HWShadeGroup struct {
+	hwShadeBase
 }
 
-func (sb hwShadeBase) lowerShade(ctx context.Context, cg protocol.CommandGroup) (any, error) {
-	pars := append([]byte(strconv.Itoa(sb.DeviceConfigCustom.ID)), ',', '3')
-	return sb.runShadeCommand(ctx, cg, pars, "lower")
+// HWShadeConfig represents the configuration for a single shade.
+type HWShade struct {
+	hwShadeBase
 }
 
-func (sb hwShadeBase) stopShade(ctx context.Context, cg protocol.CommandGroup) (any, error) {
-	pars := append([]byte(strconv.Itoa(sb.DeviceConfigCustom.ID)), ',', '4')
-	return sb.runShadeCommand(ctx, cg, pars, "stop")
+func (sg *HWShadeGroup) Operations() map[string]devices.Operation {
+	ops := sg.operations(sg.raise, sg.lower, sg.stop, sg.set)
+	if len(sg.DeviceConfigCustom.Members) > 0 {
+		ops["members"] = sg.members
+		ops["verify"] = sg.verify
+	}
+	return ops
 }
 
-func (sb hwShadeBase) setShadeLevel(ctx context.Context, cg protocol.CommandGroup, args []string) (any, error) {
-	level, err := parseShadeLevel(args)
-	if err != nil {
-		return nil, err
+func (sg *HWShadeGroup) OperationsHelp() map[string]string {
+	help := sg.hwShadeBase.OperationsHelp()
+	if len(sg.DeviceConfigCustom.Members) > 0 {
+		help["members"] = "get the level of each member of the shade group"
+		help["verify"] = "check that each member of the shade group is at the specified level"
 	}
-	pars := append([]byte(strconv.Itoa(sb.DeviceConfigCustom.ID)), ',', '1', ',')
-	pars = append(pars, []byte(strconv.Itoa(level))...)
-	return sb.runShadeCommand(ctx, cg, pars, "set")
+	return help
 }
 
-// HWShadeGroupConfig represents the configuration for a group of shades
-// as configured as a single group.
-type HWShadeGroup struct {
-	hwShadeBase
+func (sg *HWShadeGroup) levelTarget() (protocol.CommandGroup, int) {
+	return protocol.ShadeGroupCommands, sg.DeviceConfigCustom.ID
 }
 
-// HWShadeConfig represents the configuration for a single shade.
-type HWShade struct {
-	hwShadeBase
+func (sg *HWShadeGroup) integrationIDs() []integrationID {
+	return []integrationID{{"id", sg.DeviceConfigCustom.ID, protocol.ShadeGroupCommands}}
 }
 
-func (sg *HWShadeGroup) Operations() map[string]devices.Operation {
-	return sg.operations(sg.raise, sg.lower, sg.stop, sg.set)
+func (sg *HWShadeGroup) groupOperation(ctx context.Context, op string, args devices.OperationArgs) (any, error) {
+	return sg.move(ctx, protocol.ShadeGroupCommands, op, args, func(ctx context.Context, action []byte) (any, error) {
+		return sg.runGroupCommand(ctx, action, op, args)
+	})
 }
 
-func (sg *HWShadeGroup) raise(ctx context.Context, _ devices.OperationArgs) (any, error) {
-	return sg.raiseShade(ctx, protocol.ShadeGroupCommands)
+func (sg *HWShadeGroup) adjustGlare(ctx context.Context, now time.Time, args devices.OperationArgs) (GlareResult, error) {
+	return sg.hwShadeBase.adjustGlare(ctx, now, args, sg.set)
 }
 
-func (sg *HWShadeGroup) lower(ctx context.Context, _ devices.OperationArgs) (any, error) {
-	return sg.lowerShade(ctx, protocol.ShadeGroupCommands)
+func (sg *HWShadeGroup) raise(ctx context.Context, args devices.OperationArgs) (any, error) {
+	return sg.groupOperation(ctx, "raise", args)
 }
 
-func (sg *HWShadeGroup) stop(ctx context.Context, _ devices.OperationArgs) (any, error) {
-	return sg.stopShade(ctx, protocol.ShadeGroupCommands)
+func (sg *HWShadeGroup) lower(ctx context.Context, args devices.OperationArgs) (any, error) {
+	return sg.groupOperation(ctx, "lower", args)
+}
+
+func (sg *HWShadeGroup) stop(ctx context.Context, args devices.OperationArgs) (any, error) {
+	return sg.groupOperation(ctx, "stop", args)
 }
 
 func (sg *HWShadeGroup) set(ctx context.Context, args devices.OperationArgs) (any, error) {
-	return sg.setShadeLevel(ctx, protocol.ShadeGroupCommands, args.Args)
+	return sg.groupOperation(ctx, "set", args)
+}
+
+func (s *HWShade) UnmarshalYAML(node *yaml.Node) error {
+	if err := s.hwShadeBase.UnmarshalYAML(node); err != nil {
+		return err
+	}
+	if cfg := s.DeviceConfigCustom; len(cfg.Members) > 0 || cfg.FanOut {
+		return configError(s.DeviceConfigCommon, "members", "members and fan_out are only supported for shade groups")
+	}
+	return nil
 }
 
 func (s *HWShade) Operations() map[string]devices.Operation {
 	return s.operations(s.raise, s.lower, s.stop, s.set)
 }
 
-func (s *HWShade) raise(ctx context.Context, _ devices.OperationArgs) (any, error) {
-	return s.raiseShade(ctx, protocol.OutputCommands)
+func (s *HWShade) levelTarget() (protocol.CommandGroup, int) {
+	return protocol.OutputCommands, s.DeviceConfigCustom.ID
+}
+
+func (s *HWShade) integrationIDs() []integrationID {
+	return []integrationID{{"id", s.DeviceConfigCustom.ID, protocol.OutputCommands}}
+}
+
+func (s *HWShade) shadeOperation(ctx context.Context, op string, args devices.OperationArgs) (any, error) {
+	return s.move(ctx, protocol.OutputCommands, op, args, func(ctx context.Context, action []byte) (any, error) {
+		return s.runShadeCommand(ctx, protocol.OutputCommands, actionCommand(s.DeviceConfigCustom.ID, action), op)
+	})
+}
+
+func (s *HWShade) adjustGlare(ctx context.Context, now time.Time, args devices.OperationArgs) (GlareResult, error) {
+	return s.hwShadeBase.adjustGlare(ctx, now, args, s.set)
+}
+
+func (s *HWShade) raise(ctx context.Context, args devices.OperationArgs) (any, error) {
+	return s.shadeOperation(ctx, "raise", args)
 }
 
-func (s *HWShade) lower(ctx context.Context, _ devices.OperationArgs) (any, error) {
-	return s.lowerShade(ctx, protocol.OutputCommands)
+func (s *HWShade) lower(ctx context.Context, args devices.OperationArgs) (any, error) {
+	return s.shadeOperation(ctx, "lower", args)
 }
 
-func (s *HWShade) stop(ctx context.Context, _ devices.OperationArgs) (any, error) {
-	return s.stopShade(ctx, protocol.OutputCommands)
+func (s *HWShade) stop(ctx context.Context, args devices.OperationArgs) (any, error) {
+	return s.shadeOperation(ctx, "stop", args)
 }
 
 func (s *HWShade) set(ctx context.Context, args devices.OperationArgs) (any, error) {
-	return s.setShadeLevel(ctx, protocol.OutputCommands, args.Args)
+	return s.shadeOperation(ctx, "set", args)
 }
@@ -0,0 +1,251 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package homeworks
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/lutron/internal/testutil"
+)
+
+func TestParseShadeLevel(t *testing.T) {
+	for _, tc := range []struct {
+		args  []string
+		level int
+		err   bool
+	}{
+		{[]string{"0"}, 0, false},
+		{[]string{"55"}, 55, false},
+		{[]string{"100"}, 100, false},
+		{[]string{"101"}, 0, true},
+		{[]string{"-1"}, 0, true},
+		{[]string{"x"}, 0, true},
+		{nil, 0, true},
+		{[]string{"1", "2"}, 0, true},
+	} {
+		level, err := parseShadeLevel(tc.args)
+		if got, want := err != nil, tc.err; got != want {
+			t.Errorf("%v: got error %v, want error %v", tc.args, err, want)
+		}
+		if got, want := level, tc.level; got != want {
+			t.Errorf("%v: got %v, want %v", tc.args, got, want)
+		}
+	}
+}
+
+func TestShadeGroupFanOut(t *testing.T) {
+	mock := testutil.NewMockTransport(testing.Verbose())
+	mock.SetResponse("#SHADEGRP,20,1,50\r\n", "~ERROR,2\r\nQNET> ")
+	mock.SetResponse("#SHADEGRP,20,4\r\n", "QNET> ")
+	mock.SetResponse("#OUTPUT,21,1,50\r\n", "QNET> ")
+	mock.SetResponse("#OUTPUT,22,1,50\r\n", "QNET> ")
+	sg := &HWShadeGroup{}
+	sg.DeviceConfigCustom = HWShadeConfig{ID: 20, Members: []int{21, 22}, FanOut: true, Tolerance: 1}
+	ctx, sent := newMockDevice(t, mock, sg)
+	out := &bytes.Buffer{}
+
+	res, err := sg.set(ctx, devices.OperationArgs{Writer: out, Args: []string{"50"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, ok := res.(ShadeGroupResult); !ok || !r.FanOut || len(r.GroupErr) == 0 {
+		t.Errorf("unexpected result: %#v", res)
+	}
+	// No fan out if the group command succeeds.
+	res, err = sg.stop(ctx, devices.OperationArgs{Writer: out})
+	if err != nil || res != nil {
+		t.Errorf("unexpected result: %v, %v", res, err)
+	}
+	// Nor if fan out is disabled.
+	sg.DeviceConfigCustom.FanOut = false
+	if _, err := sg.set(ctx, devices.OperationArgs{Writer: out, Args: []string{"50"}}); err == nil {
+		t.Errorf("expected an error")
+	}
+	if got, want := sent(), []string{
+		"admin\r\n",
+		"#SHADEGRP,20,1,50\r\n", "#OUTPUT,21,1,50\r\n", "#OUTPUT,22,1,50\r\n",
+		"#SHADEGRP,20,4\r\n",
+		"#SHADEGRP,20,1,50\r\n",
+	}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestShadeGroupMembers(t *testing.T) {
+	mock := testutil.NewMockTransport(testing.Verbose())
+	mock.SetResponse("?OUTPUT,21,1\r\n", "~OUTPUT,21,1,50.00\r\nQNET> ")
+	mock.SetResponse("?OUTPUT,22,1\r\n", "~OUTPUT,22,1,30.50\r\nQNET> ")
+	sg := &HWShadeGroup{}
+	sg.DeviceConfigCustom = HWShadeConfig{ID: 20, Members: []int{21, 22}, Tolerance: 1}
+	ctx, _ := newMockDevice(t, mock, sg)
+	out := &bytes.Buffer{}
+
+	if _, ok := sg.Operations()["verify"]; !ok {
+		t.Errorf("missing verify operation")
+	}
+	res, err := sg.members(ctx, devices.OperationArgs{Writer: out})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := res.([]ShadeMemberLevel), []ShadeMemberLevel{{ID: 21, Level: 50}, {ID: 22, Level: 30.5}}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	res, err = sg.verify(ctx, devices.OperationArgs{Writer: out, Args: []string{"50"}})
+	if err == nil || !strings.Contains(err.Error(), "members [22] did not reach 50") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if got, want := res.(ShadeGroupVerification).Missed, []int{22}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	sg.DeviceConfigCustom.Tolerance = 20
+	if _, err := sg.verify(ctx, devices.OperationArgs{Writer: out, Args: []string{"50"}}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// levelSequence returns successive levels in response to queries for
+// an output's level.
+type levelSequence struct {
+	*testutil.MockTransport
+	query  string
+	levels []string
+}
+
+func (ls *levelSequence) Send(ctx context.Context, buf []byte) (int, error) {
+	if string(buf) == ls.query && len(ls.levels) > 0 {
+		ls.SetResponse(ls.query, ls.levels[0])
+		ls.levels = ls.levels[1:]
+	}
+	return ls.MockTransport.Send(ctx, buf)
+}
+
+// newLevelSequence returns a transport for a shade with id 30 that
+// responds to successive queries for its level with the supplied levels.
+func newLevelSequence(levels ...string) *levelSequence {
+	mock := testutil.NewMockTransport(testing.Verbose())
+	for _, cmd := range []string{"#OUTPUT,30,2\r\n", "#OUTPUT,30,4\r\n", "#OUTPUT,30,1,50\r\n"} {
+		mock.SetResponse(cmd, "QNET> ")
+	}
+	ls := &levelSequence{MockTransport: mock, query: "?OUTPUT,30,1\r\n"}
+	for _, l := range levels {
+		ls.levels = append(ls.levels, "~OUTPUT,30,1,"+l+"\r\nQNET> ")
+	}
+	return ls
+}
+
+func newTestShade() *HWShade {
+	s := &HWShade{hwShadeBase: hwShadeBase{travel: newTravelEstimator()}}
+	s.travel.poll = 10 * time.Millisecond
+	s.DeviceConfigCustom = HWShadeConfig{ID: 30}
+	return s
+}
+
+func TestShadeWait(t *testing.T) {
+	s := newTestShade()
+	ctx, sent := newMockDevice(t, newLevelSequence("0.00", "40.00", "100.00"), s)
+	s.DeviceConfigCustom.TravelTime = 200 * time.Millisecond
+	out := &bytes.Buffer{}
+
+	res, err := s.raise(ctx, devices.OperationArgs{Writer: out, Args: []string{"wait"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := res.(ShadeMotion)
+	if !m.Arrived || m.From != 0 || m.Target != 100 || m.Level != 100 || m.Estimated != 200*time.Millisecond {
+		t.Errorf("unexpected motion: %+v", m)
+	}
+	if m.Elapsed < m.Estimated {
+		t.Errorf("elapsed %v is less than estimated %v", m.Elapsed, m.Estimated)
+	}
+	if !strings.Contains(out.String(), "arrived at 100 after") {
+		t.Errorf("unexpected output: %q", out.String())
+	}
+
+	// No waiting unless requested, or for stop.
+	if res, err := s.set(ctx, devices.OperationArgs{Writer: out, Args: []string{"50"}}); err != nil || res != nil {
+		t.Errorf("unexpected result: %v, %v", res, err)
+	}
+	if res, err := s.stop(ctx, devices.OperationArgs{Writer: out, Args: []string{"wait"}}); err != nil || res != nil {
+		t.Errorf("unexpected result: %v, %v", res, err)
+	}
+	if got, want := sent(), []string{
+		"admin\r\n",
+		"?OUTPUT,30,1\r\n", "#OUTPUT,30,2\r\n", "?OUTPUT,30,1\r\n", "?OUTPUT,30,1\r\n",
+		"#OUTPUT,30,1,50\r\n",
+		"#OUTPUT,30,4\r\n",
+	}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestShadeTravelLearning(t *testing.T) {
+	s := newTestShade()
+	ctx, _ := newMockDevice(t, newLevelSequence("0.00", "25.00", "50.00"), s)
+	s.DeviceConfigCustom.Wait = true
+	res, err := s.set(ctx, devices.OperationArgs{Writer: &bytes.Buffer{}, Args: []string{"50"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := res.(ShadeMotion)
+	if !m.Arrived || m.Estimated != 0 {
+		t.Errorf("unexpected motion: %+v", m)
+	}
+	// The travel time for a full movement is extrapolated from the
+	// 50% movement.
+	if got, want := s.travel.fullTravel(0), 2*m.Elapsed; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	// A configured travel time takes precedence.
+	if got, want := s.travel.fullTravel(time.Minute), time.Minute; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	te := newTravelEstimator()
+	te.learn(5, time.Second) // too short to be used.
+	if got, want := te.fullTravel(0), time.Duration(0); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	te.learn(50, 10*time.Second)
+	te.learn(100, 40*time.Second)
+	if got, want := te.fullTravel(0), 25*time.Second; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestShadePresets(t *testing.T) {
+	s := newTestShade()
+	ctx, sent := newMockDevice(t, newLevelSequence(), s)
+	s.DeviceConfigCustom.Presets = map[string]int{"privacy": 50, "open": 100}
+	if got, want := s.OperationsHelp()["preset"], "set the shade to a preset level, one of: open (100), privacy (50), append 'wait' to wait for it to arrive"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	preset := s.Operations()["preset"]
+	if _, err := preset(ctx, devices.OperationArgs{Writer: &bytes.Buffer{}, Args: []string{"privacy"}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{nil, {"sleep"}} {
+		if _, err := preset(ctx, devices.OperationArgs{Writer: &bytes.Buffer{}, Args: args}); err == nil {
+			t.Errorf("%v: expected an error", args)
+		}
+	}
+	if got, want := sent(), []string{"admin\r\n", "#OUTPUT,30,1,50\r\n"}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	sg := &HWShadeGroup{}
+	if _, ok := sg.Operations()["preset"]; ok {
+		t.Errorf("unexpected preset operation without presets")
+	}
+	sg.DeviceConfigCustom.Presets = map[string]int{"sleep": 0}
+	if _, ok := sg.Operations()["preset"]; !ok {
+		t.Errorf("missing preset operation")
+	}
+}
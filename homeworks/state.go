@@ -0,0 +1,298 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package homeworks
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"cloudeng.io/logging/ctxlog"
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/automation/net/streamconn"
+	"github.com/cosnicolaou/lutron/protocol"
+)
+
+// StateKey identifies a cached state. For DEVICE events the component
+// is the component number reported by the event, eg. a button or LED,
+// for all other groups it is the action number so that, for example, an
+// HVAC's temperature and operating mode are cached separately.
+type StateKey struct {
+	ID        int `json:"id"`
+	Component int `json:"component"`
+}
+
+// State represents the most recently reported state of an integration ID
+// and component.
+type State struct {
+	StateKey
+	Group   protocol.CommandGroup `json:"group"`
+	Action  int                   `json:"action"`
+	Value   []string              `json:"value"`
+	Updated time.Time             `json:"updated"`
+}
+
+// StateKeyFor returns the key used to cache the supplied event.
+func StateKeyFor(ev protocol.Event) StateKey {
+	key := StateKey{ID: ev.ID}
+	if ev.Group == protocol.DeviceCommands {
+		if len(ev.Fields) > 0 {
+			key.Component, _ = strconv.Atoi(ev.Fields[0])
+		}
+		return key
+	}
+	key.Component = ev.Action()
+	return key
+}
+
+// NewState creates a State from the supplied event.
+func NewState(ev protocol.Event, when time.Time) State {
+	st := State{
+		StateKey: StateKeyFor(ev),
+		Group:    ev.Group,
+		Action:   ev.Action(),
+		Updated:  when,
+	}
+	skip := 1
+	if ev.Group == protocol.DeviceCommands {
+		skip = 2
+	}
+	if len(ev.Fields) > skip {
+		st.Value = slices.Clone(ev.Fields[skip:])
+	}
+	return st
+}
+
+func (s State) sameAs(o State) bool {
+	return s.Group == o.Group && s.Action == o.Action && slices.Equal(s.Value, o.Value)
+}
+
+// StateCache is an in-memory cache of the state of integration IDs
+// populated from monitoring events and explicit queries.
+type StateCache struct {
+	mu        sync.Mutex
+	staleness time.Duration
+	now       func() time.Time
+	states    map[StateKey]State
+	watchers  map[*stateWatcher]struct{}
+}
+
+type stateWatcher struct {
+	keys []StateKey
+	ch   chan State
+}
+
+func (w *stateWatcher) wants(key StateKey) bool {
+	return len(w.keys) == 0 || slices.Contains(w.keys, key)
+}
+
+// NewStateCache returns a new StateCache whose entries are considered
+// stale once they are older than staleness. A staleness of zero means
+// that entries never become stale.
+func NewStateCache(staleness time.Duration) *StateCache {
+	return &StateCache{
+		staleness: staleness,
+		now:       time.Now,
+		states:    map[StateKey]State{},
+		watchers:  map[*stateWatcher]struct{}{},
+	}
+}
+
+// SetStaleness sets the staleness threshold for the cache.
+func (c *StateCache) SetStaleness(staleness time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.staleness = staleness
+}
+
+// Update records the state reported by the supplied event and notifies
+// any watchers if the state has changed.
+func (c *StateCache) Update(ev protocol.Event) State {
+	st := NewState(ev, c.now())
+	c.Set(st)
+	return st
+}
+
+// Set records the supplied state and notifies any watchers if the state
+// has changed.
+func (c *StateCache) Set(st State) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	prev, ok := c.states[st.StateKey]
+	c.states[st.StateKey] = st
+	if ok && prev.sameAs(st) {
+		return
+	}
+	for w := range c.watchers {
+		if !w.wants(st.StateKey) {
+			continue
+		}
+		// Never block the cache on a slow watcher, the latest
+		// state can always be obtained via Lookup.
+		select {
+		case w.ch <- st:
+		default:
+		}
+	}
+}
+
+// Lookup returns the cached state for key, if any, regardless of
+// whether it is stale.
+func (c *StateCache) Lookup(key StateKey) (State, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	st, ok := c.states[key]
+	return st, ok
+}
+
+// LookupID returns all of the cached states for the specified
+// integration ID, ordered by component.
+func (c *StateCache) LookupID(id int) []State {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var states []State
+	for k, st := range c.states {
+		if k.ID == id {
+			states = append(states, st)
+		}
+	}
+	slices.SortFunc(states, func(a, b State) int {
+		return a.Component - b.Component
+	})
+	return states
+}
+
+// Stale returns true if the supplied state is older than the cache's
+// staleness threshold.
+func (c *StateCache) Stale(st State) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.staleness > 0 && c.now().Sub(st.Updated) > c.staleness
+}
+
+// Get returns the cached state for key if it is present and not stale,
+// otherwise it calls query to obtain the current state, caches it and
+// returns it.
+func (c *StateCache) Get(ctx context.Context, key StateKey, query func(context.Context) (protocol.Event, error)) (State, error) {
+	if st, ok := c.Lookup(key); ok && !c.Stale(st) {
+		return st, nil
+	}
+	ev, err := query(ctx)
+	if err != nil {
+		return State{}, err
+	}
+	return c.Update(ev), nil
+}
+
+// Watch returns a channel on which changes to the states for the
+// specified keys, or for all keys if none are specified, are delivered.
+// The channel is buffered with the specified size and changes are
+// dropped if the channel is full. The channel is closed when the
+// context is canceled.
+func (c *StateCache) Watch(ctx context.Context, size int, keys ...StateKey) <-chan State {
+	w := &stateWatcher{keys: slices.Clone(keys), ch: make(chan State, size)}
+	c.mu.Lock()
+	c.watchers[w] = struct{}{}
+	c.mu.Unlock()
+	go func() {
+		<-ctx.Done()
+		c.mu.Lock()
+		delete(c.watchers, w)
+		close(w.ch)
+		c.mu.Unlock()
+	}()
+	return w.ch
+}
+
+// State returns the state cache for this processor.
+func (p *QSProcessor) State() *StateCache {
+	return p.state
+}
+
+// Events returns the hub to which monitoring events received by Monitor
+// are published.
+func (p *QSProcessor) Events() *EventHub {
+	return p.events
+}
+
+// Connected returns a channel that receives a value whenever a new
+// connection to the processor is established, including the connection
+// used by Monitor. Such connections may indicate that the processor
+// has been restarted. The channel is closed when the context is canceled.
+func (p *QSProcessor) Connected(ctx context.Context) <-chan struct{} {
+	return p.connects.subscribe(ctx)
+}
+
+// DeviceState returns the cached states, if any, for all of the
+// integration IDs used by the supplied device. It never queries the
+// processor.
+func (p *QSProcessor) DeviceState(dev devices.Device) []State {
+	iu, ok := dev.(integrationIDUser)
+	if !ok {
+		return nil
+	}
+	var states []State
+	for _, iid := range iu.integrationIDs() {
+		states = append(states, p.state.LookupID(iid.id)...)
+	}
+	return states
+}
+
+// stateQuery returns the query fields used to obtain the state of the
+// specified component. DEVICE components are queried for their LED state
+// since that is the only state that can be queried for a device component.
+func stateQuery(cg protocol.CommandGroup, component int) []string {
+	if cg == protocol.DeviceCommands {
+		return []string{strconv.Itoa(component), "9"}
+	}
+	return []string{strconv.Itoa(component)}
+}
+
+// GetState returns the state of the specified integration ID and component
+// from the cache, querying the processor if there is no cached state or it
+// is stale.
+func (p *QSProcessor) GetState(ctx context.Context, cg protocol.CommandGroup, id, component int) (State, error) {
+	key := StateKey{ID: id, Component: component}
+	return p.state.Get(ctx, key, func(ctx context.Context) (protocol.Event, error) {
+		ctx, sess, err := p.session(ctx, id)
+		if err != nil {
+			return protocol.Event{}, err
+		}
+		defer sess.Release()
+		return protocol.QueryEvent(ctx, sess, cg, id, stateQuery(cg, component)...)
+	})
+}
+
+// neverIdle is used for the monitoring connection which is never
+// closed due to inactivity.
+type neverIdle struct{}
+
+func (neverIdle) Reset(context.Context) {}
+
+// Monitor opens a connection to the processor that is dedicated to
+// receiving monitoring events, uses those events to update the
+// processor's state cache and publishes them to the processor's event hub.
+// The monitoring types configured for the processor are used, or button,
+// led, zone, occupancy, sysvar and hvac if none are configured.
+// Monitor returns when the context is canceled or the connection fails;
+// it is up to the caller to call it again to reconnect.
+func (p *QSProcessor) Monitor(ctx context.Context) error {
+	ctx = ctxlog.WithAttributes(ctx, "protocol", "homeworks-qs", "monitor", true)
+	monitoring := p.monitoring
+	if len(monitoring) == 0 {
+		monitoring = defaultMonitoring
+	}
+	conn, err := p.dialAndLogin(ctx, neverIdle{}, &streamconn.SessionManager{}, monitoring)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+	return protocol.ReadEvents(ctx, conn, func(ev protocol.Event) {
+		p.state.Update(ev)
+		p.events.Publish(ev)
+	})
+}
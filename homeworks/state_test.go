@@ -0,0 +1,188 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package homeworks
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cosnicolaou/automation/net/streamconn"
+	"github.com/cosnicolaou/lutron/internal/testutil"
+	"github.com/cosnicolaou/lutron/protocol"
+)
+
+func mustParseEvent(t *testing.T, line string) protocol.Event {
+	t.Helper()
+	ev, err := protocol.ParseEvent(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ev
+}
+
+func TestStateCache(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	c := NewStateCache(time.Minute)
+	c.now = func() time.Time { return now }
+
+	all := c.Watch(ctx, 10)
+	led := c.Watch(ctx, 10, StateKey{ID: 10, Component: 81})
+
+	c.Update(mustParseEvent(t, "~OUTPUT,5,1,50.00"))
+	c.Update(mustParseEvent(t, "~OUTPUT,5,1,50.00")) // unchanged.
+	c.Update(mustParseEvent(t, "~DEVICE,10,81,9,1"))
+	c.Update(mustParseEvent(t, "~HVAC,12,1,72"))
+	c.Update(mustParseEvent(t, "~HVAC,12,3,2"))
+
+	st, ok := c.Lookup(StateKey{ID: 5, Component: 1})
+	if !ok {
+		t.Fatal("missing state")
+	}
+	if got, want := st, (State{StateKey: StateKey{ID: 5, Component: 1}, Group: protocol.OutputCommands, Action: 1, Value: []string{"50.00"}, Updated: now}); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if st, _ := c.Lookup(StateKey{ID: 12, Component: 3}); !reflect.DeepEqual(st.Value, []string{"2"}) {
+		t.Errorf("got %v", st.Value)
+	}
+
+	if got, want := len(c.LookupID(12)), 2; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	var keys []StateKey
+	for range 4 {
+		keys = append(keys, (<-all).StateKey)
+	}
+	if got, want := keys, []StateKey{{5, 1}, {10, 81}, {12, 1}, {12, 3}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := (<-led).Value, []string{"1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	select {
+	case st := <-led:
+		t.Errorf("unexpected state: %+v", st)
+	default:
+	}
+
+	queries := 0
+	query := func(context.Context) (protocol.Event, error) {
+		queries++
+		return mustParseEvent(t, "~OUTPUT,5,1,75.00"), nil
+	}
+	st, err := c.Get(ctx, StateKey{ID: 5, Component: 1}, query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := st.Value, []string{"50.00"}; !reflect.DeepEqual(got, want) || queries != 0 {
+		t.Errorf("got %v, want %v, queries %v", got, want, queries)
+	}
+	now = now.Add(2 * time.Minute)
+	st, err = c.Get(ctx, StateKey{ID: 5, Component: 1}, query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := st.Value, []string{"75.00"}; !reflect.DeepEqual(got, want) || queries != 1 {
+		t.Errorf("got %v, want %v, queries %v", got, want, queries)
+	}
+	if got, want := (<-all).Value, []string{"75.00"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	cancel()
+	for range all {
+	}
+	for range led {
+	}
+}
+
+func TestMonitorAndGetState(t *testing.T) {
+	mock := testutil.NewMockTransport(testing.Verbose())
+	mock.SetResponse("?OUTPUT,6,1\r\n", "~OUTPUT,6,1,25.00\r\nQNET> ")
+	ctx, p := newMockProcessor(t, mock)
+	p.monitoring = []protocol.MonitoringType{protocol.MonitorZone}
+	// Use a separate transport for the monitoring connection.
+	dial := p.dial
+	mon := testutil.NewMockTransport(testing.Verbose())
+	mon.SetResponse("login: ", "login: ")
+	mon.SetResponse("admin\r\n", "password: ")
+	mon.SetResponse("password\r\n", "\r\nQNET> ")
+	mon.SetResponse("#MONITORING,255,2\r\n", "~MONITORING,255,2\r\nQNET> ")
+	mon.SetResponse("#MONITORING,5,1\r\n", "~MONITORING,5,1\r\nQNET> ~OUTPUT,5,1,50.00\r\n~SHADEGRP,7,1,0.00\r\n")
+	p.dial = func(ctx context.Context, addr string, timeout time.Duration) (streamconn.Transport, error) {
+		p.dial = dial
+		if _, err := mon.Send(ctx, []byte("login: ")); err != nil {
+			return nil, err
+		}
+		return mon, nil
+	}
+
+	ch := p.State().Watch(ctx, 10)
+	mctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- p.Monitor(mctx)
+	}()
+	for range 2 {
+		<-ch
+	}
+	st, err := p.GetState(ctx, protocol.OutputCommands, 5, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := st.Value, []string{"50.00"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	st, err = p.GetState(ctx, protocol.OutputCommands, 6, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := st.Value, []string{"25.00"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	cancel()
+	if err := <-errCh; !errors.Is(err, context.Canceled) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestEventHub(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	hub := NewEventHub()
+	fast := hub.Subscribe(ctx, 10)
+	slow := hub.Subscribe(ctx, 1)
+	for _, line := range []string{"~DEVICE,10,3,3", "~DEVICE,10,3,4", "~DEVICE,10,3,3"} {
+		hub.Publish(mustParseEvent(t, line))
+	}
+	if got, want := len(fast.Events()), 3; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := fast.Dropped(), int64(0); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := (<-slow.Events()).String(), "~DEVICE,10,3,3"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := slow.Dropped(), int64(2); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := slow.Dropped(), int64(0); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	cancel()
+	for range slow.Events() {
+	}
+	for range fast.Events() {
+	}
+	if got, want := hub.Len(), 0; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,244 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package homeworks
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"cloudeng.io/logging/ctxlog"
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/automation/net/streamconn"
+	"github.com/cosnicolaou/lutron/protocol"
+	"gopkg.in/yaml.v3"
+)
+
+// ThermostatConfig represents the configuration for an HVAC controller
+// integrated with the QS processor.
+type ThermostatConfig struct {
+	ID    int    `yaml:"id"`
+	Units string `yaml:"units"` // fahrenheit (default) or celsius
+}
+
+// Thermostat represents an HVAC controller.
+type Thermostat struct {
+	devices.DeviceBase[ThermostatConfig]
+	processor *QSProcessor
+	units     protocol.TemperatureUnit
+}
+
+func (t *Thermostat) UnmarshalYAML(node *yaml.Node) error {
+	if err := node.Decode(&t.DeviceConfigCustom); err != nil {
+		return err
+	}
+	if err := validateID(t.DeviceConfigCommon, "id", t.DeviceConfigCustom.ID); err != nil {
+		return err
+	}
+	u, err := protocol.ParseTemperatureUnit(t.DeviceConfigCustom.Units)
+	if err != nil {
+		return configError(t.DeviceConfigCommon, "units", "%v", err)
+	}
+	t.units = u
+	return nil
+}
+
+func (t *Thermostat) integrationIDs() []integrationID {
+	return []integrationID{{"id", t.DeviceConfigCustom.ID, protocol.HVACCommands}}
+}
+
+func (t *Thermostat) SetController(c devices.Controller) {
+	t.processor = c.Implementation().(*QSProcessor)
+}
+
+func (t *Thermostat) ControlledBy() devices.Controller {
+	return t.processor
+}
+
+func (t *Thermostat) Operations() map[string]devices.Operation {
+	return map[string]devices.Operation{
+		"temperature": t.temperature,
+		"setpoints":   t.setpoints,
+		"mode":        t.mode,
+		"fan":         t.fan,
+		"eco":         t.eco,
+	}
+}
+
+func (t *Thermostat) OperationsHelp() map[string]string {
+	return map[string]string{
+		"temperature": "get the current temperature",
+		"setpoints":   "get the heat and cool setpoints, or set them if two temperatures are given, eg. 68 76 or 20C 24C",
+		"mode":        "get the operating mode, or set it to one of off, heat, cool, auto or em-heat",
+		"fan":         "get the fan mode, or set it to one of auto, on, cycler, no-fan, high, medium, low or top",
+		"eco":         "get the eco mode, or set it to on or off",
+	}
+}
+
+func (t *Thermostat) runOperation(ctx context.Context, op string, fn func(context.Context, *streamconn.Session) (any, error)) (any, error) {
+	ctx, sess, err := t.processor.session(ctx, t.DeviceConfigCustom.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer sess.Release()
+	grp := slog.Group("lutron", "device", "thermostat", "id", t.DeviceConfigCustom.ID, "op", op)
+	ctx = ctxlog.WithAttributes(ctx, grp)
+	return fn(ctx, sess)
+}
+
+// parseTemperature parses a temperature with an optional F or C suffix
+// and converts it to the configured units.
+func (t *Thermostat) parseTemperature(v string) (float64, error) {
+	unit := t.units
+	switch {
+	case strings.HasSuffix(v, "F"), strings.HasSuffix(v, "f"):
+		unit = protocol.Fahrenheit
+		v = v[:len(v)-1]
+	case strings.HasSuffix(v, "C"), strings.HasSuffix(v, "c"):
+		unit = protocol.Celsius
+		v = v[:len(v)-1]
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid temperature: %q", v)
+	}
+	return unit.Convert(f, t.units), nil
+}
+
+func (t *Thermostat) temperature(ctx context.Context, args devices.OperationArgs) (any, error) {
+	return t.runOperation(ctx, "temperature", func(ctx context.Context, sess *streamconn.Session) (any, error) {
+		temp, err := protocol.GetTemperature(ctx, sess, t.DeviceConfigCustom.ID, t.units)
+		if err == nil {
+			fmt.Fprintf(args.Writer, "temperature: %v %v\n", temp, t.units)
+		}
+		return struct {
+			Temperature float64 `json:"temperature"`
+			Units       string  `json:"units"`
+		}{Temperature: temp, Units: t.units.String()}, err
+	})
+}
+
+func (t *Thermostat) setpoints(ctx context.Context, args devices.OperationArgs) (any, error) {
+	var heat, cool float64
+	var err error
+	switch len(args.Args) {
+	case 0:
+	case 2:
+		if heat, err = t.parseTemperature(args.Args[0]); err != nil {
+			return nil, err
+		}
+		if cool, err = t.parseTemperature(args.Args[1]); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("must specify both heat and cool setpoints")
+	}
+	return t.runOperation(ctx, "setpoints", func(ctx context.Context, sess *streamconn.Session) (any, error) {
+		if len(args.Args) == 2 {
+			if err := protocol.SetSetpoints(ctx, sess, t.DeviceConfigCustom.ID, t.units, heat, cool); err != nil {
+				return nil, err
+			}
+		}
+		heat, cool, err := protocol.GetSetpoints(ctx, sess, t.DeviceConfigCustom.ID, t.units)
+		if err == nil {
+			fmt.Fprintf(args.Writer, "setpoints: heat %v, cool %v %v\n", heat, cool, t.units)
+		}
+		return struct {
+			Heat  float64 `json:"heat"`
+			Cool  float64 `json:"cool"`
+			Units string  `json:"units"`
+		}{Heat: heat, Cool: cool, Units: t.units.String()}, err
+	})
+}
+
+func (t *Thermostat) mode(ctx context.Context, args devices.OperationArgs) (any, error) {
+	var mode protocol.HVACMode
+	var err error
+	switch len(args.Args) {
+	case 0:
+	case 1:
+		if mode, err = protocol.ParseHVACMode(args.Args[0]); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("must specify a single mode")
+	}
+	return t.runOperation(ctx, "mode", func(ctx context.Context, sess *streamconn.Session) (any, error) {
+		if mode != 0 {
+			if err := protocol.SetOperatingMode(ctx, sess, t.DeviceConfigCustom.ID, mode); err != nil {
+				return nil, err
+			}
+		}
+		mode, err := protocol.GetOperatingMode(ctx, sess, t.DeviceConfigCustom.ID)
+		if err == nil {
+			fmt.Fprintf(args.Writer, "mode: %v\n", mode)
+		}
+		return struct {
+			Mode string `json:"mode"`
+		}{Mode: mode.String()}, err
+	})
+}
+
+func (t *Thermostat) fan(ctx context.Context, args devices.OperationArgs) (any, error) {
+	var fan protocol.HVACFan
+	var err error
+	switch len(args.Args) {
+	case 0:
+	case 1:
+		if fan, err = protocol.ParseHVACFan(args.Args[0]); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("must specify a single fan mode")
+	}
+	return t.runOperation(ctx, "fan", func(ctx context.Context, sess *streamconn.Session) (any, error) {
+		if fan != 0 {
+			if err := protocol.SetFanMode(ctx, sess, t.DeviceConfigCustom.ID, fan); err != nil {
+				return nil, err
+			}
+		}
+		fan, err := protocol.GetFanMode(ctx, sess, t.DeviceConfigCustom.ID)
+		if err == nil {
+			fmt.Fprintf(args.Writer, "fan: %v\n", fan)
+		}
+		return struct {
+			Fan string `json:"fan"`
+		}{Fan: fan.String()}, err
+	})
+}
+
+func (t *Thermostat) eco(ctx context.Context, args devices.OperationArgs) (any, error) {
+	set, on := false, false
+	switch len(args.Args) {
+	case 0:
+	case 1:
+		switch strings.ToLower(args.Args[0]) {
+		case "on":
+			set, on = true, true
+		case "off":
+			set = true
+		default:
+			return nil, fmt.Errorf("eco mode must be on or off: %q", args.Args[0])
+		}
+	default:
+		return nil, fmt.Errorf("must specify on or off")
+	}
+	return t.runOperation(ctx, "eco", func(ctx context.Context, sess *streamconn.Session) (any, error) {
+		if set {
+			if err := protocol.SetEcoMode(ctx, sess, t.DeviceConfigCustom.ID, on); err != nil {
+				return nil, err
+			}
+		}
+		on, err := protocol.GetEcoMode(ctx, sess, t.DeviceConfigCustom.ID)
+		if err == nil {
+			fmt.Fprintf(args.Writer, "eco: %v\n", on)
+		}
+		return struct {
+			Eco bool `json:"eco"`
+		}{Eco: on}, err
+	})
+}
@@ -3,3 +3,126 @@
 // license that can be found in the LICENSE file.
 
 package homeworks
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"cloudeng.io/cmdutil/keystore"
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/automation/net/streamconn"
+	"github.com/cosnicolaou/lutron/transcript"
+)
+
+// mockTransport is implemented by testutil.MockTransport and by test
+// transports that wrap it.
+type mockTransport interface {
+	streamconn.Transport
+	SetResponse(input, response string)
+}
+
+// newMockProcessor returns a QSProcessor that uses the supplied mock
+// transport rather than a telnet connection, along with a context
+// containing the credentials required to login to the mock.
+func newMockProcessor(t *testing.T, mock mockTransport) (context.Context, *QSProcessor) {
+	t.Helper()
+	ctx := keystore.ContextWithAuth(context.Background(), keystore.Keys{
+		"home": {ID: "home", User: "admin", Token: "password"},
+	})
+	mock.SetResponse("login: ", "login: ")
+	mock.SetResponse("admin\r\n", "password: ")
+	mock.SetResponse("password\r\n", "\r\nQNET> ")
+	p := NewQSProcessor(devices.Options{})
+	p.dial = func(ctx context.Context, _ string, _ time.Duration) (streamconn.Transport, error) {
+		if _, err := mock.Send(ctx, []byte("login: ")); err != nil {
+			return nil, err
+		}
+		return mock, nil
+	}
+	p.ControllerConfigCustom.KeyID = "home"
+	p.ondemand.SetKeepAlive(time.Minute)
+	return ctx, p
+}
+
+// newMockDevice sets the controller for dev to a new mock processor
+// that uses the supplied transport and records all data sent to it. It
+// returns a context to use with dev and a function that returns the
+// data sent so far, see recordSent.
+func newMockDevice(t *testing.T, mock mockTransport, dev devices.Device) (context.Context, func() []string) {
+	t.Helper()
+	ctx, p := newMockProcessor(t, mock)
+	sent := recordSent(t, p)
+	dev.SetController(p)
+	return ctx, sent
+}
+
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return bytes.Clone(b.buf.Bytes())
+}
+
+// recordSent arranges for all data sent by the processor to be recorded,
+// the returned function returns the data sent so far and may be called
+// concurrently with the processor sending data.
+func recordSent(t *testing.T, p *QSProcessor) func() []string {
+	t.Helper()
+	out := &lockedBuffer{}
+	dial := p.dial
+	p.dial = func(ctx context.Context, addr string, timeout time.Duration) (streamconn.Transport, error) {
+		conn, err := dial(ctx, addr, timeout)
+		return transcript.NewRecorder(conn, out), err
+	}
+	return func() []string {
+		entries, err := transcript.Read(bytes.NewReader(out.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var sent []string
+		for _, e := range entries {
+			if e.Direction == transcript.Send {
+				sent = append(sent, e.Data)
+			}
+		}
+		return sent
+	}
+}
+
+// cancelTransport calls cancel when the specified request is sent so
+// that tests can cancel an operation whilst a command is in flight.
+type cancelTransport struct {
+	streamconn.Transport
+	request string
+	cancel  func()
+}
+
+func (ct *cancelTransport) Send(ctx context.Context, buf []byte) (int, error) {
+	if string(buf) == ct.request {
+		ct.cancel()
+	}
+	return ct.Transport.Send(ctx, buf)
+}
+
+// cancelOnSend arranges for cancel to be called when the processor sends
+// the specified request.
+func cancelOnSend(p *QSProcessor, request string, cancel func()) {
+	dial := p.dial
+	p.dial = func(ctx context.Context, addr string, timeout time.Duration) (streamconn.Transport, error) {
+		conn, err := dial(ctx, addr, timeout)
+		return &cancelTransport{Transport: conn, request: request, cancel: cancel}, err
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package homeworks
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/lutron/protocol"
+)
+
+// configError returns an error that names the device and the configuration
+// field that is invalid.
+func configError(cfg devices.DeviceConfigCommon, field string, format string, args ...any) error {
+	return fmt.Errorf("%v %q: %v: %v", cfg.Type, cfg.Name, field, fmt.Sprintf(format, args...))
+}
+
+func validateID(cfg devices.DeviceConfigCommon, field string, id int) error {
+	if id <= 0 {
+		return configError(cfg, field, "integration id must be a positive integer, not %v", id)
+	}
+	return nil
+}
+
+func validateLevel(cfg devices.DeviceConfigCommon, field string, level float64) error {
+	if level < 0 || level > 100 {
+		return configError(cfg, field, "level must be in the range 0..100, not %v", level)
+	}
+	return nil
+}
+
+// integrationID represents an integration ID used by a device along with
+// the name of the configuration field that it was specified by and the
+// command group used to access it.
+type integrationID struct {
+	field string
+	id    int
+	group protocol.CommandGroup
+}
+
+// integrationIDUser is implemented by devices that refer to integration IDs.
+type integrationIDUser interface {
+	integrationIDs() []integrationID
+}
+
+// IntegrationIDs returns the integration IDs used by the supplied device,
+// if any.
+func IntegrationIDs(dev devices.Device) []int {
+	iu, ok := dev.(integrationIDUser)
+	if !ok {
+		return nil
+	}
+	var ids []int
+	for _, iid := range iu.integrationIDs() {
+		ids = append(ids, iid.id)
+	}
+	return ids
+}
+
+// ValidateDevices checks for problems that span multiple devices, such as
+// the same integration ID being used by more than one device. Integration
+// IDs are unique per processor and hence devices controlled by different
+// processors may use the same IDs. It is called by each QSProcessor when
+// the system is created, see QSProcessor.ConfigError, but may also be
+// called directly once all devices have been created.
+func ValidateDevices(devs map[string]devices.Device) error {
+	return validateDevices(devs, func(devices.Device) bool { return true })
+}
+
+func validateDevices(devs map[string]devices.Device, include func(devices.Device) bool) error {
+	type user struct {
+		device, field string
+	}
+	type key struct {
+		ctrl devices.Controller
+		id   int
+	}
+	names := make([]string, 0, len(devs))
+	for name := range devs {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	seen := map[key]user{}
+	var errs []error
+	for _, name := range names {
+		dev := devs[name]
+		iu, ok := dev.(integrationIDUser)
+		if !ok || !include(dev) {
+			continue
+		}
+		for _, id := range iu.integrationIDs() {
+			k := key{ctrl: dev.ControlledBy(), id: id.id}
+			if prev, ok := seen[k]; ok {
+				errs = append(errs, fmt.Errorf("device %q: %v: integration id %v is already used by device %q: %v", name, id.field, id.id, prev.device, prev.field))
+				continue
+			}
+			seen[k] = user{device: name, field: id.field}
+		}
+	}
+	return errors.Join(errs...)
+}
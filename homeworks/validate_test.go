@@ -0,0 +1,367 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package homeworks_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/lutron/homeworks"
+	"gopkg.in/yaml.v3"
+)
+
+const validateControllers = `
+controllers:
+  - name: home
+    type: homeworks-qs
+    keep_alive: 1m
+
+devices:
+`
+
+func TestValidateDevice(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		device string
+		errs   []string // empty if no error is expected.
+	}{
+		{`
+  - name: blinds
+    type: shade
+    controller: home
+    id: 3
+`, nil},
+		{`
+  - name: blinds
+    type: shade
+    controller: home
+    id: 0
+`, []string{`shade "blinds": id: integration id must be a positive integer, not 0`}},
+		{`
+  - name: all blinds
+    type: shadegrp
+    controller: home
+`, []string{`shadegrp "all blinds": id: integration id must be a positive integer, not 0`}},
+		{`
+  - name: gate
+    type: contact-closure-open-close
+    controller: home
+    open_id: 10
+    close_id: 11
+    pulse_duration: 250ms
+`, nil},
+		{`
+  - name: all blinds
+    type: shadegrp
+    controller: home
+    id: 15
+    fan_out: true
+`, []string{`shadegrp "all blinds": fan_out: requires members to be specified`}},
+		{`
+  - name: all blinds
+    type: shadegrp
+    controller: home
+    id: 15
+    members: [16, 0]
+`, []string{`shadegrp "all blinds": members[1]: integration id must be a positive integer, not 0`}},
+		{`
+  - name: blinds
+    type: shade
+    controller: home
+    id: 16
+    presets:
+      privacy: 25
+      glare: 101
+`, []string{`shade "blinds": presets.glare: level must be in the range 0..100, not 101`}},
+		{`
+  - name: all blinds
+    type: shadegrp
+    controller: home
+    id: 15
+    presets:
+      wait: 10
+`, []string{`shadegrp "all blinds": presets: invalid preset name: "wait"`}},
+		{`
+  - name: blinds
+    type: shade
+    controller: home
+    id: 16
+    travel_time: -1s
+`, []string{`shade "blinds": travel_time: must not be negative, not -1s`}},
+		{`
+  - name: blinds
+    type: shade
+    controller: home
+    id: 16
+    glare:
+      azimuth: 180
+      window_bottom: 1
+      window_top: 2.5
+      depth: 1
+`, nil},
+		{`
+  - name: blinds
+    type: shade
+    controller: home
+    id: 16
+    glare:
+      azimuth: 360
+      window_top: 2.5
+`, []string{`shade "blinds": glare.azimuth: must be in the range 0..360, not 360`}},
+		{`
+  - name: all blinds
+    type: shadegrp
+    controller: home
+    id: 15
+    glare:
+      azimuth: 90
+      window_bottom: 2
+      window_top: 1
+`, []string{`shadegrp "all blinds": glare.window_top: must be greater than window_bottom (2), not 1`}},
+		{`
+  - name: blinds
+    type: shade
+    controller: home
+    id: 16
+    members: [17]
+`, []string{`shade "blinds": members: members and fan_out are only supported for shade groups`}},
+		{`
+  - name: gate
+    type: contact-closure-open-close
+    controller: home
+    open_id: 10
+    close_id: 10
+`, []string{`contact-closure-open-close "gate": close_id: must differ from open_id: 10`}},
+		{`
+  - name: gate
+    type: contact-closure-open-close
+    controller: home
+    open_id: -1
+    close_id: 10
+`, []string{`"gate": open_id: integration id must be a positive integer, not -1`}},
+		{`
+  - name: gate
+    type: contact-closure-open-close
+    controller: home
+    open_id: 10
+    close_id: 11
+    pulse_duration: 1m
+`, []string{`"gate": pulse_duration: must be in the range 0..10s, not 1m0s`}},
+		{`
+  - name: gate
+    type: contact-closure-open-close
+    controller: home
+    open_id: 10
+    close_id: 11
+    operation_interval: -1s
+`, []string{`"gate": operation_interval: must not be negative`}},
+		{`
+  - name: doorbell
+    type: relay-momentary
+    controller: home
+    id: 13
+    pulse_duration: 20s
+`, []string{`relay-momentary "doorbell": pulse_duration: must be in the range 0..10s, not 20s`}},
+		{`
+  - name: pool pump
+    type: relay-latched
+    controller: home
+`, []string{`relay-latched "pool pump": id: integration id must be a positive integer, not 0`}},
+		{`
+  - name: scenes
+    type: phantom-button
+    controller: home
+    id: 14
+`, []string{`phantom-button "scenes": buttons: at least one button must be specified`}},
+		{`
+  - name: scenes
+    type: phantom-button
+    controller: home
+    id: 14
+    buttons:
+      party: 0
+`, []string{`phantom-button "scenes": buttons.party: component must be a positive integer, not 0`}},
+		{`
+  - name: hvac
+    type: thermostat
+    controller: home
+    id: 12
+    units: kelvin
+`, []string{`thermostat "hvac": units: unsupported temperature unit: "kelvin"`}},
+		{`
+  - name: evening
+    type: scene
+    controller: home
+    members:
+      - device: blinds
+        level: 101
+`, []string{`scene "evening": members[0].level: level must be in the range 0..100, not 101`}},
+		{`
+  - name: evening
+    type: scene
+    controller: home
+    members:
+      - level: 10
+`, []string{`scene "evening": members[0].device: device must be specified`}},
+		{`
+  - name: evening
+    type: scene
+    controller: home
+`, []string{`scene "evening": members: scene must have at least one member`}},
+	} {
+		_, _, err := createSystem(ctx, t, validateControllers+tc.device)
+		if len(tc.errs) == 0 {
+			if err != nil {
+				t.Errorf("%v: unexpected error: %v", tc.device, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("%v: expected an error", tc.device)
+			continue
+		}
+		for _, e := range tc.errs {
+			if !strings.Contains(err.Error(), e) {
+				t.Errorf("%v: error %q does not contain %q", tc.device, err, e)
+			}
+		}
+	}
+}
+
+func TestValidateDuplicateIDs(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		devices string
+		errs    []string
+	}{
+		{`
+  - name: blinds
+    type: shade
+    controller: home
+    id: 3
+  - name: all blinds
+    type: shadegrp
+    controller: home
+    id: 4
+`, nil},
+		{`
+  - name: blinds
+    type: shade
+    controller: home
+    id: 3
+  - name: all blinds
+    type: shadegrp
+    controller: home
+    id: 3
+`, []string{`device "blinds": id: integration id 3 is already used by device "all blinds": id`}},
+		{`
+  - name: a gate
+    type: contact-closure-open-close
+    controller: home
+    open_id: 10
+    close_id: 11
+  - name: b gate
+    type: contact-closure-open-close
+    controller: home
+    open_id: 11
+    close_id: 10
+  - name: hvac
+    type: thermostat
+    controller: home
+    id: 10
+`, []string{
+			`device "b gate": open_id: integration id 11 is already used by device "a gate": close_id`,
+			`device "b gate": close_id: integration id 10 is already used by device "a gate": open_id`,
+			`device "hvac": id: integration id 10 is already used by device "a gate": open_id`,
+		}},
+	} {
+		_, devs, err := createSystem(ctx, t, validateControllers+tc.devices)
+		if err != nil {
+			t.Fatalf("%v: %v", tc.devices, err)
+		}
+		err = homeworks.ValidateDevices(devs)
+		if len(tc.errs) == 0 {
+			if err != nil {
+				t.Errorf("%v: unexpected error: %v", tc.devices, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("%v: expected an error", tc.devices)
+			continue
+		}
+		for _, e := range tc.errs {
+			if !strings.Contains(err.Error(), e) {
+				t.Errorf("%v: error %q does not contain %q", tc.devices, err, e)
+			}
+		}
+	}
+}
+
+const duplicateIDSpec = `
+controllers:
+  - name: home
+    type: homeworks-qs
+    keep_alive: 1m
+  - name: office
+    type: homeworks-qs
+    keep_alive: 1m
+
+devices:
+  - name: blinds
+    type: shade
+    controller: home
+    id: 3
+  - name: all blinds
+    type: shadegrp
+    controller: home
+    id: 3
+  - name: office blinds
+    type: shade
+    controller: office
+    id: 3
+`
+
+func TestValidateDuplicateIDsAtLoad(t *testing.T) {
+	ctx := context.Background()
+	// Configuration errors are logged when the system is loaded.
+	logged := &bytes.Buffer{}
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(logged, nil)))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	var cfg devices.SystemConfig
+	if err := yaml.Unmarshal([]byte(duplicateIDSpec), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	sys, err := cfg.CreateSystem(ctx,
+		devices.WithDevices(homeworks.SupportedDevices()),
+		devices.WithControllers(homeworks.SupportedControllers()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `device "blinds": id: integration id 3 is already used by device "all blinds": id`
+	if got := logged.String(); strings.Count(got, "level=ERROR") != 1 || !strings.Contains(got, "controller=home") || !strings.Contains(got, strconv.Quote(want)) {
+		t.Errorf("unexpected log output: %s", got)
+	}
+
+	home := sys.Controllers["home"].Implementation().(*homeworks.QSProcessor)
+	office := sys.Controllers["office"].Implementation().(*homeworks.QSProcessor)
+	if err := home.ConfigError(); err == nil || err.Error() != want {
+		t.Errorf("got %v, want %v", err, want)
+	}
+	// Integration IDs are only unique per processor.
+	if err := office.ConfigError(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := homeworks.ValidateDevices(sys.Devices); err == nil || err.Error() != want {
+		t.Errorf("got %v, want %v", err, want)
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package homeworks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"cloudeng.io/logging/ctxlog"
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/automation/net/streamconn"
+	"github.com/cosnicolaou/lutron/protocol"
+)
+
+// VerifyStatus represents the outcome of verifying a single integration ID.
+type VerifyStatus string
+
+const (
+	VerifyOK        VerifyStatus = "ok"
+	VerifyMissing   VerifyStatus = "missing"
+	VerifyWrongType VerifyStatus = "wrong-type"
+	VerifyError     VerifyStatus = "error"
+)
+
+// VerifyResult represents the outcome of verifying a single integration ID
+// used by a configured device.
+type VerifyResult struct {
+	Device string       `json:"device"`
+	Field  string       `json:"field"`
+	ID     int          `json:"id"`
+	Status VerifyStatus `json:"status"`
+	Detail string       `json:"detail,omitempty"`
+}
+
+// queryIntegrationID issues a query, that has no side effects, for the
+// integration ID using the specified command group.
+func queryIntegrationID(ctx context.Context, sess *streamconn.Session, cg protocol.CommandGroup, id int) error {
+	if cg == protocol.HVACCommands {
+		_, err := protocol.GetOperatingMode(ctx, sess, id)
+		return err
+	}
+	_, err := protocol.GetLevel(ctx, sess, cg, id)
+	return err
+}
+
+func verifyIntegrationID(ctx context.Context, sess *streamconn.Session, device string, iid integrationID) VerifyResult {
+	res := VerifyResult{Device: device, Field: iid.field, ID: iid.id, Status: VerifyOK}
+	if iid.group == protocol.DeviceCommands {
+		// A DEVICE query requires a component and an action, neither of
+		// which are known for all devices, so the type of the ID is
+		// checked instead.
+		return verifyIntegrationIDType(ctx, sess, res, protocol.IntegrationIDDevice)
+	}
+	err := queryIntegrationID(ctx, sess, iid.group, iid.id)
+	if err == nil {
+		return res
+	}
+	if !errors.Is(err, protocol.ErrAccessPointObjectDoesNotExist) {
+		res.Status, res.Detail = VerifyError, err.Error()
+		return res
+	}
+	// Determine if the ID exists but refers to a different type of object.
+	return verifyIntegrationIDType(ctx, sess, res, "")
+}
+
+// verifyIntegrationIDType looks up the type of the integration ID and
+// updates res according to whether it exists and is of the expected type.
+func verifyIntegrationIDType(ctx context.Context, sess *streamconn.Session, res VerifyResult, want protocol.IntegrationIDType) VerifyResult {
+	details, err := protocol.GetIntegrationID(ctx, sess, res.ID)
+	switch {
+	case errors.Is(err, protocol.ErrAccessPointObjectDoesNotExist):
+		res.Status = VerifyMissing
+	case err != nil:
+		res.Status, res.Detail = VerifyError, err.Error()
+	case details.Type != want:
+		res.Status = VerifyWrongType
+		res.Detail = fmt.Sprintf("integration id refers to a %v", details.Type)
+	}
+	return res
+}
+
+// Verify issues a query for every integration ID used by the supplied
+// devices that are controlled by this processor and reports which IDs
+// do not exist or refer to objects of the wrong type. It is intended to
+// be run at startup or from a CLI to catch configuration errors. The
+// returned error is non-nil if a session could not be obtained or if
+// any of the IDs failed verification.
+func (p *QSProcessor) Verify(ctx context.Context, devs map[string]devices.Device) ([]VerifyResult, error) {
+	names := make([]string, 0, len(devs))
+	for name, dev := range devs {
+		if dev.ControlledBy() == devices.Controller(p) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	type deviceID struct {
+		name string
+		iid  integrationID
+	}
+	var iids []deviceID
+	var ids []int
+	for _, name := range names {
+		iu, ok := devs[name].(integrationIDUser)
+		if !ok {
+			continue
+		}
+		for _, iid := range iu.integrationIDs() {
+			iids = append(iids, deviceID{name, iid})
+			if iid.group != protocol.DeviceCommands {
+				// DEVICE ids are verified using INTEGRATIONID queries.
+				ids = append(ids, iid.id)
+			}
+		}
+	}
+	ctx, sess, err := p.session(ctx, ids...)
+	if err != nil {
+		return nil, err
+	}
+	defer sess.Release()
+	var results []VerifyResult
+	failed := 0
+	for _, di := range iids {
+		res := verifyIntegrationID(ctx, sess, di.name, di.iid)
+		if res.Status != VerifyOK {
+			failed++
+			ctxlog.Info(ctx, "verify", "device", di.name, "field", di.iid.field, "id", di.iid.id, "status", res.Status, "detail", res.Detail)
+		}
+		results = append(results, res)
+	}
+	if failed > 0 {
+		return results, fmt.Errorf("%v of %v integration ids failed verification", failed, len(results))
+	}
+	return results, nil
+}
+
+func (p *QSProcessor) verify(ctx context.Context, args devices.OperationArgs) (any, error) {
+	if err := p.ConfigError(); err != nil {
+		fmt.Fprintf(args.Writer, "configuration: %v\n", err)
+	}
+	results, err := p.Verify(ctx, p.System().Devices)
+	err = errors.Join(p.ConfigError(), err)
+	for _, r := range results {
+		if len(r.Detail) > 0 {
+			fmt.Fprintf(args.Writer, "%v: %v: %v: %v: %v\n", r.Device, r.Field, r.ID, r.Status, r.Detail)
+			continue
+		}
+		fmt.Fprintf(args.Writer, "%v: %v: %v: %v\n", r.Device, r.Field, r.ID, r.Status)
+	}
+	return results, err
+}
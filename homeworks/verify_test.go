@@ -0,0 +1,158 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package homeworks
+
+import (
+	"bytes"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/lutron/internal/testutil"
+	"github.com/cosnicolaou/lutron/protocol"
+)
+
+func TestVerify(t *testing.T) {
+	mock := testutil.NewMockTransport(testing.Verbose())
+	mock.SetResponse("?OUTPUT,3,1\r\n", "~OUTPUT,3,1,50.00\r\nQNET> ")
+	mock.SetResponse("?SHADEGRP,4,1\r\n", "~ERROR,2\r\nQNET> ")
+	mock.SetResponse("?INTEGRATIONID,3,4\r\n", "~INTEGRATIONID,4,DEVICE,0x0123ABCD\r\nQNET> ")
+	mock.SetResponse("?HVAC,5,3\r\n", "~ERROR,2\r\nQNET> ")
+	mock.SetResponse("?INTEGRATIONID,3,5\r\n", "~ERROR,2\r\nQNET> ")
+	ctx, p := newMockProcessor(t, mock)
+
+	shade := &HWShade{}
+	shade.DeviceConfigCustom.ID = 3
+	group := &HWShadeGroup{}
+	group.DeviceConfigCustom.ID = 4
+	hvac := &Thermostat{}
+	hvac.DeviceConfigCustom.ID = 5
+	devs := map[string]devices.Device{
+		"blinds": shade,
+		"group":  group,
+		"hvac":   hvac,
+	}
+	for _, d := range devs {
+		d.SetController(p)
+	}
+
+	results, err := p.Verify(ctx, devs)
+	if err == nil || err.Error() != "2 of 3 integration ids failed verification" {
+		t.Errorf("unexpected or missing error: %v", err)
+	}
+	if got, want := results, []VerifyResult{
+		{Device: "blinds", Field: "id", ID: 3, Status: VerifyOK},
+		{Device: "group", Field: "id", ID: 4, Status: VerifyWrongType, Detail: "integration id refers to a DEVICE"},
+		{Device: "hvac", Field: "id", ID: 5, Status: VerifyMissing},
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestVerifyDevices(t *testing.T) {
+	mock := testutil.NewMockTransport(testing.Verbose())
+	mock.SetResponse("?INTEGRATIONID,3,10\r\n", "~INTEGRATIONID,10,DEVICE,0x0123ABCD\r\nQNET> ")
+	mock.SetResponse("?INTEGRATIONID,3,11\r\n", "~INTEGRATIONID,11,OUTPUT,0x0A\r\nQNET> ")
+	mock.SetResponse("?INTEGRATIONID,3,12\r\n", "~INTEGRATIONID,12,DEVICE,0x0123ABCE\r\nQNET> ")
+	mock.SetResponse("?INTEGRATIONID,3,13\r\n", "~ERROR,2\r\nQNET> ")
+	ctx, p := newMockProcessor(t, mock)
+	sent := recordSent(t, p)
+
+	party, movie := &PhantomButton{}, &PhantomButton{}
+	party.DeviceConfigCustom.ID, movie.DeviceConfigCustom.ID = 10, 11
+	hall, porch := &Keypad{}, &Keypad{}
+	hall.DeviceConfigCustom.ID, porch.DeviceConfigCustom.ID = 12, 13
+	devs := map[string]devices.Device{"movie": movie, "party": party, "hall": hall, "porch": porch}
+	for _, d := range devs {
+		d.SetController(p)
+	}
+	results, err := p.Verify(ctx, devs)
+	if err == nil {
+		t.Errorf("expected an error")
+	}
+	if got, want := results, []VerifyResult{
+		{Device: "hall", Field: "id", ID: 12, Status: VerifyOK},
+		{Device: "movie", Field: "id", ID: 11, Status: VerifyWrongType, Detail: "integration id refers to a OUTPUT"},
+		{Device: "party", Field: "id", ID: 10, Status: VerifyOK},
+		{Device: "porch", Field: "id", ID: 13, Status: VerifyMissing},
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	// DEVICE ids are never queried using the malformed ?DEVICE,<id>,1.
+	if got, want := sent(), []string{
+		"admin\r\n",
+		"?INTEGRATIONID,3,12\r\n",
+		"?INTEGRATIONID,3,11\r\n",
+		"?INTEGRATIONID,3,10\r\n",
+		"?INTEGRATIONID,3,13\r\n",
+	}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestVerifyLookupErrors(t *testing.T) {
+	mock := testutil.NewMockTransport(testing.Verbose())
+	mock.SetResponse("?OUTPUT,7,1\r\n", "~ERROR,2\r\nQNET> ")
+	mock.SetResponse("?INTEGRATIONID,3,7\r\n", "~ERROR,6\r\nQNET> ")
+	ctx, p := newMockProcessor(t, mock)
+	shade := &HWShade{}
+	shade.DeviceConfigCustom.ID = 7
+	shade.SetController(p)
+
+	// Only a response stating that the object does not exist is treated
+	// as missing, all other errors are reported as such.
+	results, err := p.Verify(ctx, map[string]devices.Device{"blinds": shade})
+	if err == nil {
+		t.Errorf("expected an error")
+	}
+	if len(results) != 1 || results[0].Status != VerifyError || !strings.Contains(results[0].Detail, protocol.ErrAccessPointUnsupportedCommand.Error()) {
+		t.Errorf("unexpected results: %+v", results)
+	}
+}
+
+func TestVerifyReportsConfigErrors(t *testing.T) {
+	mock := testutil.NewMockTransport(testing.Verbose())
+	mock.SetResponse("?OUTPUT,3,1\r\n", "~OUTPUT,3,1,50.00\r\nQNET> ")
+	ctx, p := newMockProcessor(t, mock)
+	a, b := &HWShade{}, &HWShade{}
+	a.DeviceConfigCustom.ID, b.DeviceConfigCustom.ID = 3, 3
+	a.SetController(p)
+	b.SetController(p)
+	p.SetSystem(devices.System{Devices: map[string]devices.Device{"a": a, "b": b}})
+
+	out := &bytes.Buffer{}
+	_, err := p.Operations()["verify"](ctx, devices.OperationArgs{Writer: out})
+	if err == nil || !strings.Contains(err.Error(), `device "b": id: integration id 3 is already used by device "a": id`) {
+		t.Errorf("unexpected or missing error: %v", err)
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	mock := testutil.NewMockTransport(testing.Verbose())
+	mock.SetResponse("?INTEGRATIONID,3,1\r\n", "~INTEGRATIONID,1,DEVICE,0x0123ABCD\r\nQNET> ")
+	mock.SetResponse("?INTEGRATIONID,3,2\r\n", "~INTEGRATIONID,2,OUTPUT,0x0A\r\nQNET> ")
+	mock.SetResponse("?INTEGRATIONID,3,3\r\n", "~ERROR,2\r\nQNET> ")
+	mock.SetResponse("?SHADEGRP,3,1\r\n", "~ERROR,2\r\nQNET> ")
+	mock.SetResponse("?INTEGRATIONID,3,4\r\n", "~ERROR,2\r\nQNET> ")
+	mock.SetResponse("?SHADEGRP,4,1\r\n", "~SHADEGRP,4,1,100.00\r\nQNET> ")
+	ctx, p := newMockProcessor(t, mock)
+
+	inv, err := p.Discover(ctx, 1, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := inv, (Inventory{
+		Outputs:     []protocol.IntegrationIDDetails{{ID: 2, Type: protocol.IntegrationIDOutput, Serial: "0x0A"}},
+		Devices:     []protocol.IntegrationIDDetails{{ID: 1, Type: protocol.IntegrationIDDevice, Serial: "0x0123ABCD"}},
+		ShadeGroups: []protocol.IntegrationIDDetails{{ID: 4, Type: protocol.IntegrationIDShadeGroup}},
+	}); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if _, err := p.Discover(ctx, 4, 1); err == nil {
+		t.Errorf("expected an error for an invalid range")
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/cosnicolaou/lutron/homeworks"
+	"github.com/cosnicolaou/lutron/protocol"
+)
+
+// EventSource is implemented by controllers, such as homeworks.QSProcessor,
+// that publish monitoring events.
+type EventSource interface {
+	Events() *homeworks.EventHub
+}
+
+const (
+	defaultEventBuffer    = 64
+	defaultEventKeepAlive = 30 * time.Second
+)
+
+// WithEventBuffer sets the number of events buffered for each client of
+// the /events endpoint, events are dropped for clients that fall further
+// behind than this.
+func WithEventBuffer(size int) Option {
+	return func(o *options) {
+		o.eventBuffer = size
+	}
+}
+
+// Event is the JSON message sent for each monitoring event.
+type Event struct {
+	Time       time.Time          `json:"time"`
+	Controller string             `json:"controller"`
+	Type       protocol.EventType `json:"type"`
+	Devices    []string           `json:"devices,omitempty"`
+	protocol.Event
+}
+
+// Dropped is the JSON message sent when events have been dropped because
+// the client is not keeping up.
+type Dropped struct {
+	Controller string `json:"controller"`
+	Dropped    int64  `json:"dropped"`
+}
+
+type eventFilter struct {
+	devices []string
+	types   []protocol.EventType
+}
+
+func (f eventFilter) matches(ev Event) bool {
+	if len(f.types) > 0 && !slices.Contains(f.types, ev.Type) {
+		return false
+	}
+	if len(f.devices) == 0 {
+		return true
+	}
+	for _, d := range ev.Devices {
+		if slices.Contains(f.devices, d) {
+			return true
+		}
+	}
+	return false
+}
+
+// deviceNames returns a map of integration ID to the names of the devices
+// that use it for all of the devices controlled by the named controller.
+func (s *Server) deviceNames(controller string) map[int][]string {
+	names := map[int][]string{}
+	for _, name := range sortedNames(s.system.Devices) {
+		dev := s.system.Devices[name]
+		if dev.ControlledByName() != controller {
+			continue
+		}
+		for _, id := range homeworks.IntegrationIDs(dev) {
+			names[id] = append(names[id], name)
+		}
+	}
+	return names
+}
+
+type sseMessage struct {
+	event string
+	data  any
+}
+
+// events streams monitoring events as Server-Sent Events. Clients may
+// filter the events by device name, using one or more 'device'
+// parameters, and by event type using one or more 'type' parameters.
+func (s *Server) events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+	filter := eventFilter{devices: r.URL.Query()["device"]}
+	for _, t := range r.URL.Query()["type"] {
+		filter.types = append(filter.types, protocol.EventType(t))
+	}
+	for _, d := range filter.devices {
+		if _, ok := s.system.Devices[d]; !ok {
+			writeError(w, http.StatusNotFound, fmt.Errorf("unknown device: %q", d))
+			return
+		}
+	}
+	size := s.opts.eventBuffer
+	if size <= 0 {
+		size = defaultEventBuffer
+	}
+
+	ctx := r.Context()
+	out := make(chan sseMessage)
+	var wg sync.WaitGroup
+	for _, name := range sortedNames(s.system.Controllers) {
+		src, ok := s.system.Controllers[name].Implementation().(EventSource)
+		if !ok {
+			continue
+		}
+		names := s.deviceNames(name)
+		sub := src.Events().Subscribe(ctx, size)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// The subscription's buffer absorbs any delays in writing to
+			// the client, the event hub drops events rather than block.
+			for pev := range sub.Events() {
+				msgs := make([]sseMessage, 0, 2)
+				if n := sub.Dropped(); n > 0 {
+					msgs = append(msgs, sseMessage{"dropped", Dropped{Controller: name, Dropped: n}})
+				}
+				ev := Event{
+					Time:       time.Now(),
+					Controller: name,
+					Type:       pev.Type(),
+					Devices:    names[pev.ID],
+					Event:      pev,
+				}
+				if filter.matches(ev) {
+					msgs = append(msgs, sseMessage{string(ev.Type), ev})
+				}
+				for _, msg := range msgs {
+					select {
+					case out <- msg:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(defaultEventKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case msg, ok := <-out:
+			if !ok {
+				return
+			}
+			buf, err := json.Marshal(msg.data)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", msg.event, buf)
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package httpapi_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/lutron/homeworks"
+	"github.com/cosnicolaou/lutron/httpapi"
+	"github.com/cosnicolaou/lutron/protocol"
+)
+
+const eventsSpec = `
+controllers:
+  - name: home
+    type: homeworks-qs
+    keep_alive: 1m
+
+devices:
+  - name: living room
+    type: shadegrp
+    controller: home
+    id: 7
+  - name: kitchen
+    type: shade
+    controller: home
+    id: 5
+`
+
+func TestEvents(t *testing.T) {
+	ctx := context.Background()
+	system, err := devices.ParseSystemConfig(ctx, []byte(eventsSpec),
+		devices.WithDevices(homeworks.SupportedDevices()),
+		devices.WithControllers(homeworks.SupportedControllers()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hub := system.Controllers["home"].Implementation().(*homeworks.QSProcessor).Events()
+
+	ts := httptest.NewServer(httpapi.NewServer(system))
+	defer ts.Close()
+
+	if resp, err := http.Get(ts.URL + "/events?device=den"); err != nil || resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("unexpected response: %v, %v", resp, err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", ts.URL+"/events?device=living%20room&type=level", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if got, want := resp.Header.Get("Content-Type"), "text/event-stream"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	for hub.Len() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	for _, line := range []string{
+		"~OUTPUT,5,1,10.00",
+		"~SHADEGRP,7,1,50.00",
+		"~DEVICE,10,3,3",
+		"~SHADEGRP,7,1,0.00",
+	} {
+		ev, err := protocol.ParseEvent(line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hub.Publish(ev)
+	}
+
+	sc := bufio.NewScanner(resp.Body)
+	var events []httpapi.Event
+	for len(events) < 2 && sc.Scan() {
+		data, ok := strings.CutPrefix(sc.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var ev httpapi.Event
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, ev)
+	}
+	if len(events) != 2 {
+		t.Fatalf("got %v events: %v", len(events), sc.Err())
+	}
+	for i, want := range []string{"~SHADEGRP,7,1,50.00", "~SHADEGRP,7,1,0.00"} {
+		ev := events[i]
+		if got := ev.Event.String(); got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		if ev.Type != protocol.EventLevel || ev.Controller != "home" || len(ev.Devices) != 1 || ev.Devices[0] != "living room" {
+			t.Errorf("unexpected event: %+v", ev)
+		}
+	}
+}
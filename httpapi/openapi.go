@@ -0,0 +1,228 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package httpapi
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// openAPIVersion is the version of the OpenAPI specification used for
+// the generated description.
+const openAPIVersion = "3.0.3"
+
+func jsonContent(schema string) map[string]any {
+	return map[string]any{
+		"application/json": map[string]any{
+			"schema": map[string]any{"$ref": "#/components/schemas/" + schema},
+		},
+	}
+}
+
+func response(description, schema string) map[string]any {
+	return map[string]any{"description": description, "content": jsonContent(schema)}
+}
+
+func arrayResponse(description, schema string) map[string]any {
+	return map[string]any{
+		"description": description,
+		"content": map[string]any{
+			"application/json": map[string]any{
+				"schema": map[string]any{
+					"type":  "array",
+					"items": map[string]any{"$ref": "#/components/schemas/" + schema},
+				},
+			},
+		},
+	}
+}
+
+func pathParameter(name, description string) map[string]any {
+	return map[string]any{
+		"name":        name,
+		"in":          "path",
+		"required":    true,
+		"description": description,
+		"schema":      map[string]any{"type": "string"},
+	}
+}
+
+func queryParameter(name, description string) map[string]any {
+	return map[string]any{
+		"name":        name,
+		"in":          "query",
+		"required":    false,
+		"description": description,
+		"schema":      map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		"explode":     true,
+	}
+}
+
+func operationPath(summary string) map[string]any {
+	return map[string]any{
+		"post": map[string]any{
+			"summary": summary,
+			"requestBody": map[string]any{
+				"required": false,
+				"content":  jsonContent("OperationRequest"),
+			},
+			"responses": map[string]any{
+				"200": response("the result of the operation", "OperationResult"),
+				"404": response("unknown device, controller or operation", "Error"),
+				"500": response("the operation failed", "OperationResult"),
+			},
+		},
+	}
+}
+
+func schemas() map[string]any {
+	str := map[string]any{"type": "string"}
+	operation := map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"name": str, "help": str},
+	}
+	operations := map[string]any{"type": "array", "items": map[string]any{"$ref": "#/components/schemas/Operation"}}
+	return map[string]any{
+		"Operation": operation,
+		"Device": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"name":       str,
+				"type":       str,
+				"controller": str,
+				"operations": operations,
+				"state":      map[string]any{"type": "array", "items": map[string]any{"type": "object"}},
+			},
+		},
+		"Controller": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"name":       str,
+				"type":       str,
+				"operations": operations,
+			},
+		},
+		"OperationRequest": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"args": map[string]any{"type": "array", "items": str},
+			},
+		},
+		"OperationResult": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"result": map[string]any{},
+				"output": str,
+				"error":  str,
+			},
+		},
+		"SystemInfo": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"latitude":    map[string]any{"type": "number"},
+				"longitude":   map[string]any{"type": "number"},
+				"controllers": map[string]any{"type": "object", "additionalProperties": map[string]any{"$ref": "#/components/schemas/OperationResult"}},
+			},
+		},
+		"Error": map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"error": str},
+		},
+	}
+}
+
+// OpenAPI returns an OpenAPI description of the server's endpoints,
+// including a path for every operation supported by each configured
+// device and controller.
+func (s *Server) OpenAPI() map[string]any {
+	paths := map[string]any{
+		"/devices": map[string]any{
+			"get": map[string]any{
+				"summary":   "list all devices",
+				"responses": map[string]any{"200": arrayResponse("the configured devices", "Device")},
+			},
+		},
+		"/devices/{name}": map[string]any{
+			"parameters": []any{pathParameter("name", "the name of the device")},
+			"get": map[string]any{
+				"summary": "describe a device, including any cached state",
+				"responses": map[string]any{
+					"200": response("the device", "Device"),
+					"404": response("unknown device", "Error"),
+				},
+			},
+		},
+		"/controllers": map[string]any{
+			"get": map[string]any{
+				"summary":   "list all controllers",
+				"responses": map[string]any{"200": arrayResponse("the configured controllers", "Controller")},
+			},
+		},
+		"/controllers/{name}": map[string]any{
+			"parameters": []any{pathParameter("name", "the name of the controller")},
+			"get": map[string]any{
+				"summary": "describe a controller",
+				"responses": map[string]any{
+					"200": response("the controller", "Controller"),
+					"404": response("unknown controller", "Error"),
+				},
+			},
+		},
+		"/system": map[string]any{
+			"get": map[string]any{
+				"summary":   "get the system's location and the system information for each controller",
+				"responses": map[string]any{"200": response("the system information", "SystemInfo")},
+			},
+		},
+		"/events": map[string]any{
+			"get": map[string]any{
+				"summary": "stream monitoring events as Server-Sent Events",
+				"parameters": []any{
+					queryParameter("device", "only stream events for the named device, may be repeated"),
+					queryParameter("type", "only stream events of the specified type, eg. button, led, level, occupancy, sysvar or hvac, may be repeated"),
+				},
+				"responses": map[string]any{
+					"200": map[string]any{
+						"description": "a stream of events",
+						"content": map[string]any{
+							"text/event-stream": map[string]any{"schema": map[string]any{"type": "string"}},
+						},
+					},
+					"404": response("unknown device", "Error"),
+				},
+			},
+		},
+	}
+	for name, dev := range s.system.Devices {
+		for _, op := range operations(dev.Operations(), dev.OperationsHelp()) {
+			paths["/devices/"+url.PathEscape(name)+"/"+op.Name] = operationPath(op.Help)
+		}
+	}
+	for name, ctrl := range s.system.Controllers {
+		for _, op := range operations(ctrl.Operations(), ctrl.OperationsHelp()) {
+			paths["/controllers/"+url.PathEscape(name)+"/"+op.Name] = operationPath(op.Help)
+		}
+	}
+	doc := map[string]any{
+		"openapi": openAPIVersion,
+		"info": map[string]any{
+			"title":   "lutron",
+			"version": "1.0.0",
+		},
+		"paths":      paths,
+		"components": map[string]any{"schemas": schemas()},
+	}
+	if len(s.opts.token) > 0 {
+		doc["components"].(map[string]any)["securitySchemes"] = map[string]any{
+			"bearer": map[string]any{"type": "http", "scheme": "bearer"},
+		}
+		doc["security"] = []any{map[string]any{"bearer": []any{}}}
+	}
+	return doc
+}
+
+func (s *Server) openAPI(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, s.OpenAPI())
+}
@@ -0,0 +1,345 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+// Package httpapi provides an HTTP/JSON API for the devices and controllers
+// configured for a Lutron system.
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"cloudeng.io/logging/ctxlog"
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/lutron/homeworks"
+)
+
+// maxRequestBody is the largest request body accepted for an operation.
+const maxRequestBody = 64 << 10
+
+// Option represents an option to NewServer.
+type Option func(*options)
+
+type options struct {
+	token       string
+	eventBuffer int
+}
+
+// WithBearerToken requires that all requests include an
+// 'Authorization: Bearer <token>' header.
+func WithBearerToken(token string) Option {
+	return func(o *options) {
+		o.token = token
+	}
+}
+
+// Server is an http.Handler that provides the following endpoints:
+//
+//	GET  /devices                              - list all devices
+//	GET  /devices/{name}                       - describe a device, including any cached state
+//	POST /devices/{name}/{operation}           - invoke an operation on a device
+//	GET  /controllers                          - list all controllers
+//	GET  /controllers/{name}                   - describe a controller
+//	POST /controllers/{name}/{operation}       - invoke an operation on a controller
+//	GET  /system                               - the system's location and each processor's system info
+//	GET  /events                               - a Server-Sent Events stream of monitoring events
+//	GET  /openapi.json                         - an OpenAPI description of the above
+//
+// Operation arguments are supplied as a JSON object of the form
+// {"args": ["arg1", ...]}, the request body may be empty if there
+// are no arguments. The /events stream may be filtered using one or more
+// 'device' and 'type' query parameters, eg. /events?device=hall&type=button.
+type Server struct {
+	opts   options
+	system devices.System
+	mux    *http.ServeMux
+}
+
+// NewServer returns a new Server for the supplied system.
+func NewServer(system devices.System, opts ...Option) *Server {
+	s := &Server{system: system, mux: http.NewServeMux()}
+	for _, fn := range opts {
+		fn(&s.opts)
+	}
+	s.mux.HandleFunc("GET /devices", s.listDevices)
+	s.mux.HandleFunc("GET /devices/{name}", s.getDevice)
+	s.mux.HandleFunc("POST /devices/{name}/{operation}", s.deviceOperation)
+	s.mux.HandleFunc("GET /controllers", s.listControllers)
+	s.mux.HandleFunc("GET /controllers/{name}", s.getController)
+	s.mux.HandleFunc("POST /controllers/{name}/{operation}", s.controllerOperation)
+	s.mux.HandleFunc("GET /system", s.getSystem)
+	s.mux.HandleFunc("GET /events", s.events)
+	s.mux.HandleFunc("GET /openapi.json", s.openAPI)
+	return s
+}
+
+// Handle registers an additional handler with the server's mux, it
+// is subject to the same authentication as all other endpoints.
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="lutron"`)
+		writeError(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	if len(s.opts.token) == 0 {
+		return true
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.opts.token)) == 1
+}
+
+// Operation describes an operation supported by a device or controller.
+type Operation struct {
+	Name string `json:"name"`
+	Help string `json:"help,omitempty"`
+}
+
+// Device describes a configured device.
+type Device struct {
+	Name       string            `json:"name"`
+	Type       string            `json:"type"`
+	Controller string            `json:"controller"`
+	Operations []Operation       `json:"operations"`
+	State      []homeworks.State `json:"state,omitempty"`
+}
+
+// Controller describes a configured controller.
+type Controller struct {
+	Name       string      `json:"name"`
+	Type       string      `json:"type"`
+	Operations []Operation `json:"operations"`
+}
+
+// OperationResult is returned for every operation invoked.
+type OperationResult struct {
+	Result any    `json:"result,omitempty"`
+	Output string `json:"output,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type operationRequest struct {
+	Args []string `json:"args"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func operations(ops map[string]devices.Operation, help map[string]string) []Operation {
+	names := make([]string, 0, len(ops))
+	for name := range ops {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	out := make([]Operation, len(names))
+	for i, name := range names {
+		out[i] = Operation{Name: name, Help: help[name]}
+	}
+	return out
+}
+
+func sortedNames[T any](m map[string]T) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func describeDevice(dev devices.Device) Device {
+	cfg := dev.Config()
+	return Device{
+		Name:       cfg.Name,
+		Type:       cfg.Type,
+		Controller: cfg.ControllerName,
+		Operations: operations(dev.Operations(), dev.OperationsHelp()),
+	}
+}
+
+func describeController(ctrl devices.Controller) Controller {
+	cfg := ctrl.Config()
+	return Controller{
+		Name:       cfg.Name,
+		Type:       cfg.Type,
+		Operations: operations(ctrl.Operations(), ctrl.OperationsHelp()),
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+func (s *Server) listDevices(w http.ResponseWriter, _ *http.Request) {
+	out := []Device{}
+	for _, name := range sortedNames(s.system.Devices) {
+		out = append(out, describeDevice(s.system.Devices[name]))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) getDevice(w http.ResponseWriter, r *http.Request) {
+	dev, ok := s.system.Devices[r.PathValue("name")]
+	if !ok {
+		writeError(w, http.StatusNotFound, errors.New("unknown device"))
+		return
+	}
+	desc := describeDevice(dev)
+	if isControlled(dev) {
+		if p, ok := dev.ControlledBy().Implementation().(*homeworks.QSProcessor); ok {
+			desc.State = p.DeviceState(dev)
+		}
+	}
+	writeJSON(w, http.StatusOK, desc)
+}
+
+// isControlled returns true if the device has a controller. A device
+// whose controller was never set returns a typed nil.
+func isControlled(dev devices.Device) bool {
+	ctrl := dev.ControlledBy()
+	if ctrl == nil {
+		return false
+	}
+	v := reflect.ValueOf(ctrl)
+	return v.Kind() != reflect.Pointer || !v.IsNil()
+}
+
+func (s *Server) listControllers(w http.ResponseWriter, _ *http.Request) {
+	out := []Controller{}
+	for _, name := range sortedNames(s.system.Controllers) {
+		out = append(out, describeController(s.system.Controllers[name]))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) getController(w http.ResponseWriter, r *http.Request) {
+	ctrl, ok := s.system.Controllers[r.PathValue("name")]
+	if !ok {
+		writeError(w, http.StatusNotFound, errors.New("unknown controller"))
+		return
+	}
+	writeJSON(w, http.StatusOK, describeController(ctrl))
+}
+
+func (s *Server) deviceOperation(w http.ResponseWriter, r *http.Request) {
+	dev, ok := s.system.Devices[r.PathValue("name")]
+	if !ok {
+		writeError(w, http.StatusNotFound, errors.New("unknown device"))
+		return
+	}
+	if !isControlled(dev) {
+		writeError(w, http.StatusConflict, errors.New("device has no controller"))
+		return
+	}
+	s.invoke(w, r, dev.Operations())
+}
+
+func (s *Server) controllerOperation(w http.ResponseWriter, r *http.Request) {
+	ctrl, ok := s.system.Controllers[r.PathValue("name")]
+	if !ok {
+		writeError(w, http.StatusNotFound, errors.New("unknown controller"))
+		return
+	}
+	s.invoke(w, r, ctrl.Operations())
+}
+
+func (s *Server) invoke(w http.ResponseWriter, r *http.Request, ops map[string]devices.Operation) {
+	name := r.PathValue("operation")
+	op, ok := ops[name]
+	if !ok {
+		writeError(w, http.StatusNotFound, errors.New("unknown operation"))
+		return
+	}
+	var req operationRequest
+	body := http.MaxBytesReader(w, r.Body, maxRequestBody)
+	if err := json.NewDecoder(body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		var mbe *http.MaxBytesError
+		if errors.As(err, &mbe) {
+			writeError(w, http.StatusRequestEntityTooLarge, err)
+			return
+		}
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	ctx := ctxlog.WithAttributes(r.Context(), "http", r.URL.Path)
+	res, out, err := s.run(ctx, op, req.Args)
+	result := OperationResult{Result: res, Output: out}
+	if err != nil {
+		result.Error = err.Error()
+		writeJSON(w, http.StatusInternalServerError, result)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) run(ctx context.Context, op devices.Operation, args []string) (any, string, error) {
+	var out bytes.Buffer
+	res, err := op(ctx, devices.OperationArgs{
+		Due:    time.Now(),
+		Place:  s.system.Location.Place,
+		Writer: &out,
+		Args:   args,
+	})
+	return res, out.String(), err
+}
+
+// SystemInfo is returned by the /system endpoint.
+type SystemInfo struct {
+	Latitude    float64                    `json:"latitude"`
+	Longitude   float64                    `json:"longitude"`
+	Controllers map[string]OperationResult `json:"controllers,omitempty"`
+}
+
+// systemInfoOperation is the controller operation used to obtain the
+// system information for the /system endpoint.
+const systemInfoOperation = "getsysteminfo"
+
+func (s *Server) getSystem(w http.ResponseWriter, r *http.Request) {
+	info := SystemInfo{
+		Latitude:    s.system.Location.Latitude,
+		Longitude:   s.system.Location.Longitude,
+		Controllers: map[string]OperationResult{},
+	}
+	for _, name := range sortedNames(s.system.Controllers) {
+		op, ok := s.system.Controllers[name].Operations()[systemInfoOperation]
+		if !ok {
+			continue
+		}
+		res, _, err := s.run(r.Context(), op, nil)
+		result := OperationResult{Result: res}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		info.Controllers[name] = result
+	}
+	writeJSON(w, http.StatusOK, info)
+}
@@ -0,0 +1,224 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package httpapi_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/lutron/homeworks"
+	"github.com/cosnicolaou/lutron/httpapi"
+)
+
+type testController struct {
+	devices.ControllerBase[struct{}]
+}
+
+func (c *testController) Implementation() any { return c }
+
+func (c *testController) Operations() map[string]devices.Operation {
+	return map[string]devices.Operation{
+		"getsysteminfo": func(context.Context, devices.OperationArgs) (any, error) {
+			return map[string]string{"os": "8.52"}, nil
+		},
+	}
+}
+
+func (c *testController) OperationsHelp() map[string]string {
+	return map[string]string{"getsysteminfo": "get system info"}
+}
+
+type testDevice struct {
+	devices.DeviceBase[struct{}]
+	ctrl devices.Controller
+}
+
+func (d *testDevice) SetController(c devices.Controller) { d.ctrl = c }
+
+func (d *testDevice) ControlledBy() devices.Controller { return d.ctrl }
+
+func (d *testDevice) Operations() map[string]devices.Operation {
+	return map[string]devices.Operation{
+		"echo": func(_ context.Context, args devices.OperationArgs) (any, error) {
+			fmt.Fprintf(args.Writer, "%v", strings.Join(args.Args, " "))
+			return args.Args, nil
+		},
+		"fail": func(context.Context, devices.OperationArgs) (any, error) {
+			return nil, errors.New("oops")
+		},
+	}
+}
+
+func (d *testDevice) OperationsHelp() map[string]string {
+	return map[string]string{"echo": "echo the arguments", "fail": "always fails"}
+}
+
+func newTestSystem() devices.System {
+	ctrl := &testController{}
+	ctrl.SetConfig(devices.ControllerConfigCommon{Name: "home", Type: "test"})
+	dev := &testDevice{}
+	dev.SetConfig(devices.DeviceConfigCommon{Name: "living room", Type: "test", ControllerName: "home"})
+	dev.SetController(ctrl)
+	return devices.System{
+		Controllers: map[string]devices.Controller{"home": ctrl},
+		Devices:     map[string]devices.Device{"living room": dev},
+	}
+}
+
+func do(t *testing.T, srv http.Handler, method, path, token, body string, v any) int {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if len(token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	if v != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("%v %v: %v: %s", method, path, err, rec.Body.Bytes())
+		}
+	}
+	return rec.Code
+}
+
+func TestServer(t *testing.T) {
+	srv := httpapi.NewServer(newTestSystem())
+
+	var devs []httpapi.Device
+	if got, want := do(t, srv, "GET", "/devices", "", "", &devs), http.StatusOK; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	want := httpapi.Device{
+		Name:       "living room",
+		Type:       "test",
+		Controller: "home",
+		Operations: []httpapi.Operation{{"echo", "echo the arguments"}, {"fail", "always fails"}},
+	}
+	if got := devs; !reflect.DeepEqual(got, []httpapi.Device{want}) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	var dev httpapi.Device
+	if got, want := do(t, srv, "GET", "/devices/living%20room", "", "", &dev), http.StatusOK; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got := dev; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if got, want := do(t, srv, "GET", "/devices/kitchen", "", "", nil), http.StatusNotFound; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	var res httpapi.OperationResult
+	if got, want := do(t, srv, "POST", "/devices/living%20room/echo", "", `{"args": ["a", "b"]}`, &res), http.StatusOK; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := res, (httpapi.OperationResult{Result: []any{"a", "b"}, Output: "a b"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	res = httpapi.OperationResult{}
+	if got, want := do(t, srv, "POST", "/devices/living%20room/fail", "", "", &res), http.StatusInternalServerError; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := res.Error, "oops"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := do(t, srv, "POST", "/devices/living%20room/missing", "", "", nil), http.StatusNotFound; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := do(t, srv, "POST", "/devices/living%20room/echo", "", "{", nil), http.StatusBadRequest; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	large := `{"args": ["` + strings.Repeat("x", 1<<20) + `"]}`
+	if got, want := do(t, srv, "POST", "/devices/living%20room/echo", "", large, nil), http.StatusRequestEntityTooLarge; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	var ctrls []httpapi.Controller
+	if got, want := do(t, srv, "GET", "/controllers", "", "", &ctrls), http.StatusOK; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if len(ctrls) != 1 || ctrls[0].Name != "home" {
+		t.Errorf("unexpected controllers: %+v", ctrls)
+	}
+	var info httpapi.SystemInfo
+	if got, want := do(t, srv, "GET", "/system", "", "", &info), http.StatusOK; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := info.Controllers["home"].Result, any(map[string]any{"os": "8.52"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestServerUncontrolledDevice(t *testing.T) {
+	system := newTestSystem()
+	// A device whose controller was never set has a typed nil controller.
+	shade := &homeworks.HWShade{}
+	shade.SetConfig(devices.DeviceConfigCommon{Name: "blinds", Type: "shade"})
+	system.Devices["blinds"] = shade
+	srv := httpapi.NewServer(system)
+	var dev httpapi.Device
+	if got, want := do(t, srv, "GET", "/devices/blinds", "", "", &dev), http.StatusOK; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if dev.Name != "blinds" || len(dev.State) != 0 {
+		t.Errorf("unexpected device: %+v", dev)
+	}
+	var res struct {
+		Error string `json:"error"`
+	}
+	if got, want := do(t, srv, "POST", "/devices/blinds/raise", "", "", &res), http.StatusConflict; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := res.Error, "device has no controller"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestServerAuth(t *testing.T) {
+	srv := httpapi.NewServer(newTestSystem(), httpapi.WithBearerToken("s3cret"))
+	for _, tc := range []struct {
+		token string
+		code  int
+	}{
+		{"", http.StatusUnauthorized},
+		{"wrong", http.StatusUnauthorized},
+		{"s3cret", http.StatusOK},
+	} {
+		if got, want := do(t, srv, "GET", "/devices", tc.token, "", nil), tc.code; got != want {
+			t.Errorf("%q: got %v, want %v", tc.token, got, want)
+		}
+	}
+}
+
+func TestOpenAPI(t *testing.T) {
+	srv := httpapi.NewServer(newTestSystem(), httpapi.WithBearerToken("s3cret"))
+	var doc struct {
+		OpenAPI string                    `json:"openapi"`
+		Paths   map[string]map[string]any `json:"paths"`
+	}
+	if got, want := do(t, srv, "GET", "/openapi.json", "s3cret", "", &doc), http.StatusOK; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for _, path := range []string{
+		"/devices", "/devices/{name}", "/system",
+		"/devices/living%20room/echo", "/devices/living%20room/fail",
+		"/controllers/home/getsysteminfo",
+	} {
+		if _, ok := doc.Paths[path]; !ok {
+			t.Errorf("missing path %v", path)
+		}
+	}
+	if got, want := doc.Paths["/devices/living%20room/echo"]["post"].(map[string]any)["summary"], "echo the arguments"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
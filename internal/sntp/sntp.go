@@ -0,0 +1,71 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+// Package sntp provides a minimal SNTP (RFC 4330) client sufficient
+// to obtain the current time from an NTP server.
+package sntp
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+)
+
+// ntpEpochOffset is the number of seconds between the NTP epoch (1900)
+// and the Unix epoch (1970).
+const ntpEpochOffset = 2208988800
+
+func ntpTime(b []byte) time.Time {
+	secs := binary.BigEndian.Uint32(b[0:4])
+	frac := binary.BigEndian.Uint32(b[4:8])
+	nsec := (int64(frac) * 1e9) >> 32
+	return time.Unix(int64(secs)-ntpEpochOffset, nsec)
+}
+
+// Query returns the current time as reported by the specified server,
+// adjusted for half of the round trip time of the request. The server
+// may be specified as host or host:port, the default port being 123.
+func Query(ctx context.Context, server string) (time.Time, error) {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "123")
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", server)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer conn.Close()
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(5 * time.Second)
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return time.Time{}, err
+	}
+	req := make([]byte, 48)
+	req[0] = 0x23 // LI = 0, VN = 4, Mode = 3 (client).
+	start := time.Now()
+	if _, err := conn.Write(req); err != nil {
+		return time.Time{}, err
+	}
+	resp := make([]byte, 48)
+	n, err := conn.Read(resp)
+	if err != nil {
+		return time.Time{}, err
+	}
+	rtt := time.Since(start)
+	if n < 48 {
+		return time.Time{}, fmt.Errorf("short response from %v: %v bytes", server, n)
+	}
+	if mode := resp[0] & 0x07; mode != 4 {
+		return time.Time{}, fmt.Errorf("unexpected mode in response from %v: %v", server, mode)
+	}
+	if stratum := resp[1]; stratum == 0 {
+		return time.Time{}, fmt.Errorf("kiss-of-death response from %v", server)
+	}
+	// Use the transmit timestamp.
+	return ntpTime(resp[40:48]).Add(rtt / 2), nil
+}
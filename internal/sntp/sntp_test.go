@@ -0,0 +1,53 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package sntp_test
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/cosnicolaou/lutron/internal/sntp"
+)
+
+func serveOnce(t *testing.T, conn net.PacketConn, now time.Time) {
+	buf := make([]byte, 48)
+	_, addr, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	resp := make([]byte, 48)
+	resp[0] = 0x24 // VN = 4, Mode = 4 (server).
+	resp[1] = 1    // stratum.
+	secs := uint64(now.Unix() + 2208988800)
+	frac := (uint64(now.Nanosecond()) << 32) / 1e9
+	binary.BigEndian.PutUint32(resp[40:44], uint32(secs))
+	binary.BigEndian.PutUint32(resp[44:48], uint32(frac))
+	if _, err := conn.WriteTo(resp, addr); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	then := time.Date(2025, 3, 1, 12, 0, 0, 500_000_000, time.UTC)
+	go serveOnce(t, conn, then)
+	now, err := sntp.Query(ctx, conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := now.Sub(then); d < 0 || d > time.Second {
+		t.Errorf("got %v, want %v", now, then)
+	}
+}
@@ -53,10 +53,20 @@ func (m *MockTransport) SendSensitive(_ context.Context, buf []byte) (int, error
 	return len(response), nil
 }
 
-func (m *MockTransport) ReadUntil(_ context.Context, expected []string) ([]byte, error) {
+func (m *MockTransport) ReadUntil(ctx context.Context, expected []string) ([]byte, error) {
 	seen := []byte{}
 	m.log("reading until %v\n", expected)
-	for r := range m.connCh {
+	for {
+		var r byte
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case b, ok := <-m.connCh:
+			if !ok {
+				return nil, io.EOF
+			}
+			r = b
+		}
 		seen = append(seen, r)
 		m.log("% 30q\n", seen)
 		for _, t := range expected {
@@ -66,7 +76,6 @@ func (m *MockTransport) ReadUntil(_ context.Context, expected []string) ([]byte,
 			}
 		}
 	}
-	return nil, io.EOF
 }
 
 func (m *MockTransport) Close(context.Context) error {
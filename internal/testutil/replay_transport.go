@@ -0,0 +1,111 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package testutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/cosnicolaou/lutron/transcript"
+)
+
+// ErrReplayMismatch is returned when the operations performed on a
+// ReplayTransport do not match those in the transcript being replayed.
+var ErrReplayMismatch = errors.New("replay mismatch")
+
+// ReplayTransport is a streamconn.Transport that replays a previously
+// recorded transcript. Data sent must match that recorded and the data
+// returned by ReadUntil is that which was recorded.
+type ReplayTransport struct {
+	mu      sync.Mutex
+	entries []transcript.Entry
+	next    int
+}
+
+// NewReplayTransport returns a ReplayTransport for the supplied entries.
+func NewReplayTransport(entries []transcript.Entry) *ReplayTransport {
+	return &ReplayTransport{entries: entries}
+}
+
+// NewReplayTransportFromFile returns a ReplayTransport for the transcript
+// in the named file.
+func NewReplayTransportFromFile(filename string) (*ReplayTransport, error) {
+	entries, err := transcript.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return NewReplayTransport(entries), nil
+}
+
+// Remaining returns the number of entries that have not been replayed.
+func (r *ReplayTransport) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.entries) - r.next
+}
+
+func (r *ReplayTransport) expect(dir transcript.Direction) (transcript.Entry, error) {
+	if r.next >= len(r.entries) {
+		return transcript.Entry{}, io.EOF
+	}
+	e := r.entries[r.next]
+	if e.Direction != dir {
+		return e, fmt.Errorf("%w: entry %v: got %v, want %v", ErrReplayMismatch, r.next, dir, e.Direction)
+	}
+	r.next++
+	return e, nil
+}
+
+func (r *ReplayTransport) Send(_ context.Context, buf []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, err := r.expect(transcript.Send)
+	if err != nil {
+		return 0, err
+	}
+	if e.Data != string(buf) {
+		return 0, fmt.Errorf("%w: entry %v: sent %q, recorded %q", ErrReplayMismatch, r.next-1, buf, e.Data)
+	}
+	return len(buf), nil
+}
+
+func (r *ReplayTransport) SendSensitive(_ context.Context, buf []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := r.expect(transcript.Sensitive); err != nil {
+		return 0, err
+	}
+	return len(buf), nil
+}
+
+func (r *ReplayTransport) ReadUntil(_ context.Context, _ []string) ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.next < len(r.entries) && r.entries[r.next].Direction == transcript.Error {
+		e := r.entries[r.next]
+		r.next++
+		if e.Data == io.EOF.Error() {
+			return nil, io.EOF
+		}
+		return nil, errors.New(e.Data)
+	}
+	e, err := r.expect(transcript.Recv)
+	if err != nil {
+		return nil, err
+	}
+	// A recorded error may follow a partial read.
+	if r.next < len(r.entries) && r.entries[r.next].Direction == transcript.Error {
+		err = errors.New(r.entries[r.next].Data)
+		r.next++
+	}
+	return []byte(e.Data), err
+}
+
+func (r *ReplayTransport) Close(context.Context) error {
+	return nil
+}
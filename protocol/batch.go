@@ -0,0 +1,92 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package protocol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/cosnicolaou/automation/net/streamconn"
+)
+
+// Result represents the outcome of a single command issued as part
+// of a batch.
+type Result struct {
+	Response string
+	Err      error
+}
+
+// Results represents the outcome of all of the commands in a batch.
+type Results []Result
+
+// Err returns the errors, if any, encountered by the commands in the batch
+// joined using errors.Join. Each error is annotated with the index of
+// the command in the batch.
+func (r Results) Err() error {
+	var errs []error
+	for i, res := range r {
+		if res.Err != nil {
+			errs = append(errs, fmt.Errorf("command %v: %w", i, res.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Failed returns the number of commands that failed.
+func (r Results) Failed() int {
+	n := 0
+	for _, res := range r {
+		if res.Err != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// IsQuery returns true if the command is a query ('?') rather than a
+// set ('#') command, ie. one for which a response is expected.
+func (c Command) IsQuery() bool {
+	return c.req[0] == '?'
+}
+
+// Batch sends all of the supplied commands back-to-back on the supplied
+// session and then reads the response to each in turn. A response is
+// required for queries but is not expected for set commands. An error
+// on the underlying connection is reported for all commands whose
+// responses have not been read. Commands that could not be sent because
+// the context was canceled whilst waiting for a rate limiter report
+// the context's error.
+func Batch(ctx context.Context, s *streamconn.Session, cmds ...Command) Results {
+	results := make(Results, len(cmds))
+	queries := make([]bool, len(cmds))
+	sent := len(cmds)
+	for i, cmd := range cmds {
+		if err := waitForRateLimit(ctx, cmd.request()); err != nil {
+			for j := i; j < len(cmds); j++ {
+				results[j].Err = err
+			}
+			sent = i
+			break
+		}
+		queries[i] = cmd.IsQuery()
+		s.Send(ctx, cmd.request())
+	}
+	for i, cmd := range cmds[:sent] {
+		response, err := s.ReadUntil(ctx, qsPromptStr)
+		if err != nil {
+			for j := i; j < sent; j++ {
+				results[j].Err = err
+			}
+			break
+		}
+		r, err := ParseResponse(cmd.responsePrefix(), response)
+		if err == nil && queries[i] && r == "" {
+			err = ErrorNullParsedResponse
+		}
+		results[i] = Result{Response: r, Err: err}
+	}
+	return results
+}
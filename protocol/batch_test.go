@@ -0,0 +1,47 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package protocol_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cosnicolaou/automation/net/netutil"
+	"github.com/cosnicolaou/automation/net/streamconn"
+	"github.com/cosnicolaou/lutron/internal/testutil"
+	"github.com/cosnicolaou/lutron/protocol"
+)
+
+func TestBatch(t *testing.T) {
+	ctx := context.Background()
+
+	mock := testutil.NewMockTransport(testing.Verbose())
+	mock.SetResponse("#OUTPUT,1,1,50\r\n", "~OUTPUT,1,1,50.00\r\nQNET> ")
+	mock.SetResponse("?OUTPUT,2,1\r\n", "~OUTPUT,1,1,50.00\r\nQNET> ")
+	mock.SetResponse("?OUTPUT,3,1\r\n", "~OUTPUT,3,1,75.00\r\nQNET> ")
+
+	mgr := &streamconn.SessionManager{}
+	s := mgr.New(mock, netutil.NewIdleTimer(10))
+	defer s.Release()
+
+	results := protocol.Batch(ctx, s,
+		protocol.NewCommand(protocol.OutputCommands, true, []byte("1,1,50")),
+		protocol.NewCommand(protocol.OutputCommands, false, []byte("2,1")),
+		protocol.NewCommand(protocol.OutputCommands, false, []byte("3,1")),
+	)
+	if err := results[0].Err; err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := results[1].Err; !errors.Is(err, protocol.ErrorNullParsedResponse) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if got, want := results[2].Response, "75.00"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := results.Failed(), 1; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -28,6 +28,8 @@ var (
 	ErrAccessPointUnsupportedCommand  = errors.New("access point unsupported command")
 )
 
+var errorPrefix = []byte("~ERROR")
+
 // ParseError parses an error message from the Lutron system of
 // the form: ~ERROR,Error Number
 func ParseError(s string) error {
@@ -69,25 +71,41 @@ func parseResponseLine(cmd, response []byte) (string, error) {
 
 // ParseResponse parses a possibly multi-line response from the Lutron system
 // looking for a response to the issued command. There may be multiple other
-// responses due to monitoring outpot from the system.
+// responses due to monitoring outpot from the system. Errors are reported
+// by the system as ~ERROR,<n> without the command as a prefix and are
+// returned as the corresponding error, but only if the error is the last
+// line of the response, ie. the reply to the command immediately preceding
+// the prompt. Earlier errors belong to other requests and are ignored.
 func ParseResponse(cmd, response []byte) (string, error) {
-	var line []byte
+	var line, last []byte
 	for _, b := range response {
 		if b == 0x00 { // the QS responses sometimes include leading null byte
 			continue
 		}
 		if b == '\r' || b == '\n' {
-			if !bytes.HasPrefix(line, cmd) {
-				// Unrelated messages, most likely monitoring notifications.
-				line = line[:0]
+			if len(line) == 0 {
 				continue
 			}
-			return parseResponseLine(cmd, line)
+			if bytes.HasPrefix(line, cmd) {
+				return parseResponseLine(cmd, line)
+			}
+			// Unrelated messages, most likely monitoring notifications.
+			last = append(last[:0], line...)
+			line = line[:0]
+			continue
 		}
 		line = append(line, b)
 	}
-	if len(line) > 0 && bytes.HasPrefix(line, cmd) {
-		return parseResponseLine(cmd, line)
+	if len(line) > 0 && !bytes.HasPrefix(line, qsPrompt) {
+		// A trailing line that is not the prompt.
+		if bytes.HasPrefix(line, cmd) {
+			return parseResponseLine(cmd, line)
+		}
+		last = line
+	}
+	if bytes.HasPrefix(last, errorPrefix) {
+		// Errors are reported without the command prefix.
+		return "", ParseError(string(last))
 	}
 	return "", nil
 }
@@ -100,6 +118,10 @@ const (
 	OutputCommands
 	MonitorCommands
 	ShadeGroupCommands
+	HVACCommands
+	IntegrationIDCommands
+	GroupCommands
+	SysvarCommands
 )
 
 type Command struct {
@@ -118,9 +140,17 @@ func (cg CommandGroup) appendTo(b []byte) []byte {
 	case OutputCommands:
 		return append(b, "OUTPUT"...)
 	case MonitorCommands:
-		return append(b, "MONITOR"...)
+		return append(b, "MONITORING"...)
 	case ShadeGroupCommands:
 		return append(b, "SHADEGRP"...)
+	case HVACCommands:
+		return append(b, "HVAC"...)
+	case IntegrationIDCommands:
+		return append(b, "INTEGRATIONID"...)
+	case GroupCommands:
+		return append(b, "GROUP"...)
+	case SysvarCommands:
+		return append(b, "SYSVAR"...)
 	}
 	return b
 }
@@ -154,6 +184,12 @@ func (c Command) request() []byte {
 	return c.req
 }
 
+// IntegrationID returns the integration ID that the command refers to,
+// or zero if it does not refer to one.
+func (c Command) IntegrationID() int {
+	return requestIntegrationID(c.req)
+}
+
 // The protocol response always includes the original command as a prefix.
 func (c Command) responsePrefix() []byte {
 	if c.custom != nil {
@@ -165,8 +201,12 @@ func (c Command) responsePrefix() []byte {
 }
 
 // Call sends the command to the Lutron system, waits for a prompt
-// and returns the response.
+// and returns the response. If the context carries a RateLimiter then
+// Call waits for it before sending the command.
 func (c Command) Call(ctx context.Context, s *streamconn.Session) (string, error) {
+	if err := waitForRateLimit(ctx, c.request()); err != nil {
+		return "", err
+	}
 	s.Send(ctx, c.request())
 	response, err := s.ReadUntil(ctx, "QNET> ")
 	if err != nil {
@@ -183,8 +223,12 @@ func (c Command) Call(ctx context.Context, s *streamconn.Session) (string, error
 }
 
 // Invoke sends the command to the Lutron system, waits for a prompt
-// and returns. A response is not expected.
+// and returns. A response is not expected. If the context carries a
+// RateLimiter then Invoke waits for it before sending the command.
 func (c Command) Invoke(ctx context.Context, s *streamconn.Session) error {
+	if err := waitForRateLimit(ctx, c.request()); err != nil {
+		return err
+	}
 	s.Send(ctx, c.request())
 	response, err := s.ReadUntil(ctx, "QNET> ")
 	if err != nil {
@@ -193,3 +237,20 @@ func (c Command) Invoke(ctx context.Context, s *streamconn.Session) error {
 	_, err = ParseResponse(c.responsePrefix(), response)
 	return err
 }
+
+// Raw sends the supplied command line, without a trailing \r\n, to the
+// Lutron system and returns the unparsed response with the trailing
+// prompt removed. It is intended for relaying commands on behalf of
+// other clients and hence does not interpret errors in the response.
+func Raw(ctx context.Context, s *streamconn.Session, line string) (string, error) {
+	req := []byte(line + "\r\n")
+	if err := waitForRateLimit(ctx, req); err != nil {
+		return "", err
+	}
+	s.Send(ctx, req)
+	response, err := s.ReadUntil(ctx, qsPromptStr)
+	if err != nil {
+		return "", err
+	}
+	return string(bytes.TrimSuffix(response, qsPrompt)), nil
+}
@@ -0,0 +1,48 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package protocol
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/cosnicolaou/automation/net/streamconn"
+)
+
+// DeviceAction sends a '#DEVICE,<id>,<component>,<action>' command to the
+// Lutron system, eg. to press or release a keypad button.
+func DeviceAction(ctx context.Context, s *streamconn.Session, id, component, action int) error {
+	pars := make([]byte, 0, 16)
+	pars = strconv.AppendInt(pars, int64(id), 10)
+	pars = append(pars, ',')
+	pars = strconv.AppendInt(pars, int64(component), 10)
+	pars = append(pars, ',')
+	pars = strconv.AppendInt(pars, int64(action), 10)
+	return NewCommand(DeviceCommands, true, pars).Invoke(ctx, s)
+}
+
+// LEDState represents the state of a keypad LED.
+type LEDState int
+
+const (
+	LEDOff LEDState = iota
+	LEDOn
+	LEDFlash
+	LEDRapidFlash
+)
+
+// SetLED sends a '#DEVICE,<id>,<component>,9,<state>' command to the
+// Lutron system to set the state of a keypad LED.
+func SetLED(ctx context.Context, s *streamconn.Session, id, component int, state LEDState) error {
+	pars := make([]byte, 0, 16)
+	pars = strconv.AppendInt(pars, int64(id), 10)
+	pars = append(pars, ',')
+	pars = strconv.AppendInt(pars, int64(component), 10)
+	pars = append(pars, ',')
+	pars = strconv.AppendInt(pars, DeviceActionLEDState, 10)
+	pars = append(pars, ',')
+	pars = strconv.AppendInt(pars, int64(state), 10)
+	return NewCommand(DeviceCommands, true, pars).Invoke(ctx, s)
+}
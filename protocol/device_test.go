@@ -0,0 +1,37 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package protocol_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cosnicolaou/automation/net/netutil"
+	"github.com/cosnicolaou/automation/net/streamconn"
+	"github.com/cosnicolaou/lutron/internal/testutil"
+	"github.com/cosnicolaou/lutron/protocol"
+)
+
+func TestDeviceAction(t *testing.T) {
+	ctx := context.Background()
+
+	mock := testutil.NewMockTransport(testing.Verbose())
+	mock.SetResponse("#DEVICE,20,4,3\r\n", "QNET> ")
+	mock.SetResponse("#DEVICE,20,4,4\r\n", "QNET> ")
+	mock.SetResponse("#DEVICE,20,84,9,1\r\n", "QNET> ")
+
+	mgr := &streamconn.SessionManager{}
+	s := mgr.New(mock, netutil.NewIdleTimer(10))
+	defer s.Release()
+
+	for _, action := range []int{protocol.DeviceActionPress, protocol.DeviceActionRelease} {
+		if err := protocol.DeviceAction(ctx, s, 20, 4, action); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := protocol.SetLED(ctx, s, 20, 84, protocol.LEDOn); err != nil {
+		t.Fatal(err)
+	}
+}
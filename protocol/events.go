@@ -0,0 +1,232 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package protocol
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/cosnicolaou/automation/net/streamconn"
+)
+
+// String returns the name of the command group as used by the protocol,
+// eg. OUTPUT.
+func (cg CommandGroup) String() string {
+	return string(cg.appendTo(nil))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (cg CommandGroup) MarshalText() ([]byte, error) {
+	return cg.appendTo(nil), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (cg *CommandGroup) UnmarshalText(text []byte) error {
+	g, err := ParseCommandGroup(string(text))
+	if err != nil {
+		return err
+	}
+	*cg = g
+	return nil
+}
+
+var commandGroups = map[string]CommandGroup{
+	"SYSTEM":        SystemCommands,
+	"DEVICE":        DeviceCommands,
+	"OUTPUT":        OutputCommands,
+	"MONITORING":    MonitorCommands,
+	"SHADEGRP":      ShadeGroupCommands,
+	"HVAC":          HVACCommands,
+	"INTEGRATIONID": IntegrationIDCommands,
+	"GROUP":         GroupCommands,
+	"SYSVAR":        SysvarCommands,
+}
+
+// ParseCommandGroup parses the protocol name of a command group, eg. OUTPUT.
+func ParseCommandGroup(s string) (CommandGroup, error) {
+	if cg, ok := commandGroups[strings.ToUpper(s)]; ok {
+		return cg, nil
+	}
+	return 0, fmt.Errorf("unknown command group: %q", s)
+}
+
+// Event represents a message from the Lutron system that reports the state
+// of an integration ID. Such messages are sent unsolicited when monitoring
+// is enabled and in response to queries and are of the form
+// ~<GROUP>,<id>,<field>,... Fields contains the comma separated values
+// following the integration ID, eg. for ~OUTPUT,5,1,50.00, Fields is
+// [1 50.00].
+type Event struct {
+	Group  CommandGroup `json:"group"`
+	ID     int          `json:"id"`
+	Fields []string     `json:"fields"`
+}
+
+// Action returns the action number of the event, for DEVICE events the
+// action follows the component number.
+func (e Event) Action() int {
+	idx := 0
+	if e.Group == DeviceCommands {
+		idx = 1
+	}
+	if idx >= len(e.Fields) {
+		return 0
+	}
+	a, _ := strconv.Atoi(e.Fields[idx])
+	return a
+}
+
+// EventType represents a broad classification of events.
+type EventType string
+
+const (
+	EventButton    EventType = "button"    // keypad button press, release, hold or multi-tap.
+	EventLED       EventType = "led"       // keypad LED state change.
+	EventLevel     EventType = "level"     // output or shade group level change.
+	EventOccupancy EventType = "occupancy" // occupancy group state change.
+	EventSysvar    EventType = "sysvar"    // system variable change.
+	EventHVAC      EventType = "hvac"      // HVAC state change.
+	EventOther     EventType = "other"
+)
+
+// Device actions used to classify events.
+const (
+	DeviceActionPress    = 3
+	DeviceActionRelease  = 4
+	DeviceActionHold     = 5
+	DeviceActionMultiTap = 6
+	DeviceActionLEDState = 9
+)
+
+// Type returns the classification of the event.
+func (e Event) Type() EventType {
+	switch e.Group {
+	case DeviceCommands:
+		switch e.Action() {
+		case DeviceActionPress, DeviceActionRelease, DeviceActionHold, DeviceActionMultiTap:
+			return EventButton
+		case DeviceActionLEDState:
+			return EventLED
+		}
+	case OutputCommands, ShadeGroupCommands:
+		if e.Action() == int(OutputLevel) {
+			return EventLevel
+		}
+	case GroupCommands:
+		return EventOccupancy
+	case SysvarCommands:
+		return EventSysvar
+	case HVACCommands:
+		return EventHVAC
+	}
+	return EventOther
+}
+
+func (e Event) String() string {
+	var out strings.Builder
+	out.WriteByte('~')
+	out.WriteString(e.Group.String())
+	out.WriteByte(',')
+	out.WriteString(strconv.Itoa(e.ID))
+	for _, f := range e.Fields {
+		out.WriteByte(',')
+		out.WriteString(f)
+	}
+	return out.String()
+}
+
+// ParseEvent parses a single line of the form ~<GROUP>,<id>,<field>,...
+// ~ERROR lines and groups that do not refer to integration IDs, such as
+// ~SYSTEM, are not events and result in an error.
+func ParseEvent(line string) (Event, error) {
+	line = strings.TrimSpace(strings.Trim(line, "\x00"))
+	if !strings.HasPrefix(line, "~") {
+		return Event{}, fmt.Errorf("not an event: %q", line)
+	}
+	parts := strings.Split(line[1:], ",")
+	if len(parts) < 3 {
+		return Event{}, fmt.Errorf("malformed event: %q", line)
+	}
+	cg, err := ParseCommandGroup(parts[0])
+	if err != nil {
+		return Event{}, fmt.Errorf("not an event: %q: %w", line, err)
+	}
+	switch cg {
+	case DeviceCommands, OutputCommands, ShadeGroupCommands, HVACCommands, GroupCommands, SysvarCommands:
+	default:
+		return Event{}, fmt.Errorf("not an event: %q", line)
+	}
+	id, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return Event{}, fmt.Errorf("malformed integration id in event: %q", line)
+	}
+	return Event{Group: cg, ID: id, Fields: parts[2:]}, nil
+}
+
+// QueryEvent issues a query of the form ?<GROUP>,<id>,<field>,... and
+// returns the response as an Event whose Fields include the fields
+// of the query, eg. QueryEvent(ctx, s, OutputCommands, 5, "1") may
+// return an event with Fields [1 50.00].
+func QueryEvent(ctx context.Context, s *streamconn.Session, cg CommandGroup, id int, fields ...string) (Event, error) {
+	pars := strconv.AppendInt(make([]byte, 0, 32), int64(id), 10)
+	for _, f := range fields {
+		pars = append(pars, ',')
+		pars = append(pars, f...)
+	}
+	r, err := NewCommand(cg, false, pars).Call(ctx, s)
+	if err != nil {
+		return Event{}, err
+	}
+	ev := Event{Group: cg, ID: id, Fields: make([]string, 0, len(fields)+1)}
+	ev.Fields = append(ev.Fields, fields...)
+	for _, f := range strings.Split(r, ",") {
+		ev.Fields = append(ev.Fields, strings.TrimSpace(f))
+	}
+	return ev, nil
+}
+
+// ReadEvents reads lines from the supplied transport, which must be
+// dedicated to receiving monitoring output, and calls fn for every event
+// received. Lines that are not events, such as prompts, are ignored.
+// Read timeouts are ignored since the transport may be idle for long
+// periods. ReadEvents returns when the context is canceled or when any
+// other error is encountered. Since a read may not return until its
+// timeout expires, ReadEvents closes the transport when the context is
+// canceled so that a blocked read returns promptly.
+func ReadEvents(ctx context.Context, t streamconn.Transport, fn func(Event)) error {
+	stop := context.AfterFunc(ctx, func() {
+		t.Close(context.WithoutCancel(ctx)) //nolint:errcheck
+	})
+	defer stop()
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		buf, err := t.ReadUntil(ctx, []string{"\r\n"})
+		if err != nil {
+			if cerr := ctx.Err(); cerr != nil {
+				return cerr
+			}
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				continue
+			}
+			return err
+		}
+		// Prompts are not followed by a newline and hence will
+		// precede the next line.
+		buf = bytes.TrimLeft(buf, "\x00")
+		for bytes.HasPrefix(buf, []byte("QNET> ")) {
+			buf = buf[len("QNET> "):]
+		}
+		if ev, err := ParseEvent(string(buf)); err == nil {
+			fn(ev)
+		}
+	}
+}
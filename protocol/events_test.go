@@ -0,0 +1,154 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package protocol_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/cosnicolaou/automation/net/netutil"
+	"github.com/cosnicolaou/automation/net/streamconn"
+	"github.com/cosnicolaou/lutron/internal/testutil"
+	"github.com/cosnicolaou/lutron/protocol"
+	"github.com/cosnicolaou/lutron/transcript"
+)
+
+func TestParseEvent(t *testing.T) {
+	for _, tc := range []struct {
+		line   string
+		event  protocol.Event
+		action int
+		typ    protocol.EventType
+	}{
+		{"~OUTPUT,5,1,50.00", protocol.Event{Group: protocol.OutputCommands, ID: 5, Fields: []string{"1", "50.00"}}, 1, protocol.EventLevel},
+		{"\x00~SHADEGRP,7,1,0.00\r\n", protocol.Event{Group: protocol.ShadeGroupCommands, ID: 7, Fields: []string{"1", "0.00"}}, 1, protocol.EventLevel},
+		{"~DEVICE,10,3,9,1", protocol.Event{Group: protocol.DeviceCommands, ID: 10, Fields: []string{"3", "9", "1"}}, 9, protocol.EventLED},
+		{"~DEVICE,10,3,3", protocol.Event{Group: protocol.DeviceCommands, ID: 10, Fields: []string{"3", "3"}}, 3, protocol.EventButton},
+		{"~HVAC,12,3,2", protocol.Event{Group: protocol.HVACCommands, ID: 12, Fields: []string{"3", "2"}}, 3, protocol.EventHVAC},
+		{"~GROUP,20,3,3", protocol.Event{Group: protocol.GroupCommands, ID: 20, Fields: []string{"3", "3"}}, 3, protocol.EventOccupancy},
+		{"~SYSVAR,30,1,2", protocol.Event{Group: protocol.SysvarCommands, ID: 30, Fields: []string{"1", "2"}}, 1, protocol.EventSysvar},
+	} {
+		ev, err := protocol.ParseEvent(tc.line)
+		if err != nil {
+			t.Errorf("%q: %v", tc.line, err)
+			continue
+		}
+		if got, want := ev, tc.event; !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: got %+v, want %+v", tc.line, got, want)
+		}
+		if got, want := ev.Action(), tc.action; got != want {
+			t.Errorf("%q: got %v, want %v", tc.line, got, want)
+		}
+		if got, want := ev.Type(), tc.typ; got != want {
+			t.Errorf("%q: got %v, want %v", tc.line, got, want)
+		}
+	}
+
+	for _, line := range []string{
+		"", "QNET> ", "~ERROR,2", "~SYSTEM,1,10:00:00", "~OUTPUT,5", "~OUTPUT,x,1,2", "~UNKNOWN,1,2,3",
+	} {
+		if _, err := protocol.ParseEvent(line); err == nil {
+			t.Errorf("%q: expected an error", line)
+		}
+	}
+
+	ev := protocol.Event{Group: protocol.DeviceCommands, ID: 10, Fields: []string{"3", "9", "1"}}
+	if got, want := ev.String(), "~DEVICE,10,3,9,1"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	buf, err := json.Marshal(ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded protocol.Event
+	if err := json.Unmarshal(buf, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, ev) {
+		t.Errorf("got %+v, want %+v", decoded, ev)
+	}
+}
+
+func TestQueryEvent(t *testing.T) {
+	ctx := context.Background()
+	mock := testutil.NewMockTransport(testing.Verbose())
+	mock.SetResponse("?OUTPUT,5,1\r\n", "~OUTPUT,5,1,50.00\r\nQNET> ")
+	mock.SetResponse("?DEVICE,10,3,9\r\n", "~DEVICE,10,3,9,1\r\nQNET> ")
+	mgr := &streamconn.SessionManager{}
+	s := mgr.New(mock, netutil.NewIdleTimer(10))
+	defer s.Release()
+
+	ev, err := protocol.QueryEvent(ctx, s, protocol.OutputCommands, 5, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := ev.String(), "~OUTPUT,5,1,50.00"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	ev, err = protocol.QueryEvent(ctx, s, protocol.DeviceCommands, 10, "3", "9")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := ev.String(), "~DEVICE,10,3,9,1"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestReadEvents(t *testing.T) {
+	ctx := context.Background()
+	recv := func(data string) transcript.Entry {
+		return transcript.Entry{Direction: transcript.Recv, Data: data}
+	}
+	rt := testutil.NewReplayTransport([]transcript.Entry{
+		recv("~OUTPUT,5,1,50.00\r\n"),
+		recv("QNET> ~SHADEGRP,7,1,0.00\r\n"),
+		recv("~MONITORING,5,1\r\n"),
+		recv("\x00QNET> QNET> ~DEVICE,10,3,3\r\n"),
+	})
+	var got []string
+	err := protocol.ReadEvents(ctx, rt, func(ev protocol.Event) {
+		got = append(got, ev.String())
+	})
+	if !errors.Is(err, io.EOF) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if want := []string{"~OUTPUT,5,1,50.00", "~SHADEGRP,7,1,0.00", "~DEVICE,10,3,3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// blockingTransport is a transport whose reads ignore the context and
+// only return once the transport is closed.
+type blockingTransport struct {
+	streamconn.Transport
+	closed chan struct{}
+}
+
+func (bt *blockingTransport) ReadUntil(context.Context, []string) ([]byte, error) {
+	<-bt.closed
+	return nil, io.EOF
+}
+
+func (bt *blockingTransport) Close(context.Context) error {
+	close(bt.closed)
+	return nil
+}
+
+func TestReadEventsCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	bt := &blockingTransport{closed: make(chan struct{})}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- protocol.ReadEvents(ctx, bt, func(protocol.Event) {})
+	}()
+	cancel()
+	if err := <-errCh; !errors.Is(err, context.Canceled) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
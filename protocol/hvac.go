@@ -0,0 +1,317 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package protocol
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/cosnicolaou/automation/net/streamconn"
+)
+
+// HVACActions represents the actions supported by the HVAC command group.
+// See https://assets.lutron.com/a/documents/040249.pdf, HVAC Controller.
+type HVACActions int
+
+const (
+	HVACTemperatureF  HVACActions = 1
+	HVACSetpointsF    HVACActions = 2
+	HVACOperatingMode HVACActions = 3
+	HVACFanMode       HVACActions = 4
+	HVACEcoMode       HVACActions = 5
+	HVACTemperatureC  HVACActions = 14
+	HVACSetpointsC    HVACActions = 15
+)
+
+// TemperatureUnit represents the units used for temperatures.
+type TemperatureUnit int
+
+const (
+	Fahrenheit TemperatureUnit = iota
+	Celsius
+)
+
+// ParseTemperatureUnit parses a temperature unit of the form
+// fahrenheit, f, celsius or c (case insensitive). An empty string
+// is treated as fahrenheit.
+func ParseTemperatureUnit(u string) (TemperatureUnit, error) {
+	switch strings.ToLower(u) {
+	case "", "f", "fahrenheit":
+		return Fahrenheit, nil
+	case "c", "celsius":
+		return Celsius, nil
+	}
+	return Fahrenheit, fmt.Errorf("unsupported temperature unit: %q", u)
+}
+
+func (u TemperatureUnit) String() string {
+	if u == Celsius {
+		return "celsius"
+	}
+	return "fahrenheit"
+}
+
+// Convert converts the temperature t expressed in units u to the units
+// specified by to.
+func (u TemperatureUnit) Convert(t float64, to TemperatureUnit) float64 {
+	switch {
+	case u == to:
+		return t
+	case to == Celsius:
+		return (t - 32) * 5 / 9
+	default:
+		return t*9/5 + 32
+	}
+}
+
+func (u TemperatureUnit) temperatureAction() HVACActions {
+	if u == Celsius {
+		return HVACTemperatureC
+	}
+	return HVACTemperatureF
+}
+
+func (u TemperatureUnit) setpointsAction() HVACActions {
+	if u == Celsius {
+		return HVACSetpointsC
+	}
+	return HVACSetpointsF
+}
+
+// HVACMode represents the operating mode of an HVAC controller.
+type HVACMode int
+
+const (
+	HVACModeOff HVACMode = iota + 1
+	HVACModeHeat
+	HVACModeCool
+	HVACModeAuto
+	HVACModeEmHeat
+	HVACModeLockedOut
+	HVACModeFan
+	HVACModeDry
+)
+
+var hvacModeNames = map[HVACMode]string{
+	HVACModeOff:       "off",
+	HVACModeHeat:      "heat",
+	HVACModeCool:      "cool",
+	HVACModeAuto:      "auto",
+	HVACModeEmHeat:    "em-heat",
+	HVACModeLockedOut: "locked-out",
+	HVACModeFan:       "fan",
+	HVACModeDry:       "dry",
+}
+
+func (m HVACMode) String() string {
+	if n, ok := hvacModeNames[m]; ok {
+		return n
+	}
+	return fmt.Sprintf("unknown-mode(%d)", int(m))
+}
+
+// ParseHVACMode parses one of the modes that can be set on an HVAC
+// controller, ie. off, heat, cool, auto or em-heat.
+func ParseHVACMode(m string) (HVACMode, error) {
+	for _, mode := range []HVACMode{HVACModeOff, HVACModeHeat, HVACModeCool, HVACModeAuto, HVACModeEmHeat} {
+		if strings.EqualFold(m, hvacModeNames[mode]) {
+			return mode, nil
+		}
+	}
+	return 0, fmt.Errorf("unsupported hvac mode: %q", m)
+}
+
+// HVACFan represents the fan mode of an HVAC controller.
+type HVACFan int
+
+const (
+	HVACFanAuto HVACFan = iota + 1
+	HVACFanOn
+	HVACFanCycler
+	HVACFanNoFan
+	HVACFanHigh
+	HVACFanMedium
+	HVACFanLow
+	HVACFanTop
+)
+
+var hvacFanNames = map[HVACFan]string{
+	HVACFanAuto:   "auto",
+	HVACFanOn:     "on",
+	HVACFanCycler: "cycler",
+	HVACFanNoFan:  "no-fan",
+	HVACFanHigh:   "high",
+	HVACFanMedium: "medium",
+	HVACFanLow:    "low",
+	HVACFanTop:    "top",
+}
+
+func (f HVACFan) String() string {
+	if n, ok := hvacFanNames[f]; ok {
+		return n
+	}
+	return fmt.Sprintf("unknown-fan(%d)", int(f))
+}
+
+// ParseHVACFan parses a fan mode, eg. auto, on, low etc.
+func ParseHVACFan(f string) (HVACFan, error) {
+	for fan, name := range hvacFanNames {
+		if strings.EqualFold(f, name) {
+			return fan, nil
+		}
+	}
+	return 0, fmt.Errorf("unsupported hvac fan mode: %q", f)
+}
+
+// HVAC sends a '[#?]HVAC,<id>,<action>[,<parameters>...]' command to the
+// Lutron system. For queries the response, excluding the command prefix,
+// is returned.
+func HVAC(ctx context.Context, s *streamconn.Session, set bool, id int, action HVACActions, parameters ...string) (string, error) {
+	pars := make([]byte, 0, 32)
+	pars = strconv.AppendInt(pars, int64(id), 10)
+	pars = append(pars, ',')
+	pars = strconv.AppendInt(pars, int64(action), 10)
+	for _, p := range parameters {
+		pars = append(pars, ',')
+		pars = append(pars, p...)
+	}
+	cmd := NewCommand(HVACCommands, set, pars)
+	if set {
+		return "", cmd.Invoke(ctx, s)
+	}
+	r, err := cmd.Call(ctx, s)
+	if err != nil {
+		return "", fmt.Errorf("hvac %v, action %v: %w", id, action, err)
+	}
+	return r, nil
+}
+
+func parseHVACInts(r string, n int) ([]int, error) {
+	parts := strings.Split(r, ",")
+	if len(parts) < n {
+		return nil, fmt.Errorf("unexpected response: %q", r)
+	}
+	vals := make([]int, n)
+	for i := range n {
+		v, err := strconv.Atoi(parts[i])
+		if err != nil {
+			return nil, fmt.Errorf("unexpected response: %q: %w", r, err)
+		}
+		vals[i] = v
+	}
+	return vals, nil
+}
+
+// formatTemperature formats a temperature to at most one decimal place
+// to avoid sending artifacts of unit conversions to the processor.
+func formatTemperature(t float64) string {
+	return strconv.FormatFloat(math.Round(t*10)/10, 'f', -1, 64)
+}
+
+// GetTemperature returns the current temperature reported by the HVAC
+// controller in the requested units.
+func GetTemperature(ctx context.Context, s *streamconn.Session, id int, unit TemperatureUnit) (float64, error) {
+	r, err := HVAC(ctx, s, false, id, unit.temperatureAction())
+	if err != nil {
+		return 0, err
+	}
+	t, err := strconv.ParseFloat(r, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse temperature: %q: %w", r, err)
+	}
+	return t, nil
+}
+
+// GetSetpoints returns the heat and cool setpoints in the requested units.
+func GetSetpoints(ctx context.Context, s *streamconn.Session, id int, unit TemperatureUnit) (heat, cool float64, err error) {
+	r, err := HVAC(ctx, s, false, id, unit.setpointsAction())
+	if err != nil {
+		return 0, 0, err
+	}
+	parts := strings.Split(r, ",")
+	if len(parts) < 2 {
+		return 0, 0, fmt.Errorf("unexpected response: %q", r)
+	}
+	if heat, err = strconv.ParseFloat(parts[0], 64); err != nil {
+		return 0, 0, fmt.Errorf("failed to parse heat setpoint: %q: %w", r, err)
+	}
+	if cool, err = strconv.ParseFloat(parts[1], 64); err != nil {
+		return 0, 0, fmt.Errorf("failed to parse cool setpoint: %q: %w", r, err)
+	}
+	return heat, cool, nil
+}
+
+// SetSetpoints sets the heat and cool setpoints in the specified units.
+func SetSetpoints(ctx context.Context, s *streamconn.Session, id int, unit TemperatureUnit, heat, cool float64) error {
+	if heat > cool {
+		return fmt.Errorf("heat setpoint %v must not exceed cool setpoint %v", heat, cool)
+	}
+	_, err := HVAC(ctx, s, true, id, unit.setpointsAction(), formatTemperature(heat), formatTemperature(cool))
+	return err
+}
+
+// GetOperatingMode returns the current operating mode.
+func GetOperatingMode(ctx context.Context, s *streamconn.Session, id int) (HVACMode, error) {
+	r, err := HVAC(ctx, s, false, id, HVACOperatingMode)
+	if err != nil {
+		return 0, err
+	}
+	v, err := parseHVACInts(r, 1)
+	if err != nil {
+		return 0, err
+	}
+	return HVACMode(v[0]), nil
+}
+
+// SetOperatingMode sets the operating mode.
+func SetOperatingMode(ctx context.Context, s *streamconn.Session, id int, mode HVACMode) error {
+	_, err := HVAC(ctx, s, true, id, HVACOperatingMode, strconv.Itoa(int(mode)))
+	return err
+}
+
+// GetFanMode returns the current fan mode.
+func GetFanMode(ctx context.Context, s *streamconn.Session, id int) (HVACFan, error) {
+	r, err := HVAC(ctx, s, false, id, HVACFanMode)
+	if err != nil {
+		return 0, err
+	}
+	v, err := parseHVACInts(r, 1)
+	if err != nil {
+		return 0, err
+	}
+	return HVACFan(v[0]), nil
+}
+
+// SetFanMode sets the fan mode.
+func SetFanMode(ctx context.Context, s *streamconn.Session, id int, fan HVACFan) error {
+	_, err := HVAC(ctx, s, true, id, HVACFanMode, strconv.Itoa(int(fan)))
+	return err
+}
+
+// GetEcoMode returns true if eco (setback) mode is enabled.
+func GetEcoMode(ctx context.Context, s *streamconn.Session, id int) (bool, error) {
+	r, err := HVAC(ctx, s, false, id, HVACEcoMode)
+	if err != nil {
+		return false, err
+	}
+	v, err := parseHVACInts(r, 1)
+	if err != nil {
+		return false, err
+	}
+	return v[0] == 2, nil
+}
+
+// SetEcoMode enables or disables eco (setback) mode.
+func SetEcoMode(ctx context.Context, s *streamconn.Session, id int, on bool) error {
+	v := "1"
+	if on {
+		v = "2"
+	}
+	_, err := HVAC(ctx, s, true, id, HVACEcoMode, v)
+	return err
+}
@@ -0,0 +1,117 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package protocol_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cosnicolaou/automation/net/netutil"
+	"github.com/cosnicolaou/automation/net/streamconn"
+	"github.com/cosnicolaou/lutron/internal/testutil"
+	"github.com/cosnicolaou/lutron/protocol"
+)
+
+func TestHVAC(t *testing.T) {
+	ctx := context.Background()
+
+	mock := testutil.NewMockTransport(testing.Verbose())
+	mock.SetResponse("?HVAC,10,1\r\n", "~HVAC,10,1,72\r\nQNET> ")
+	mock.SetResponse("?HVAC,10,14\r\n", "~OUTPUT,3,1,100.00\r\n~HVAC,10,14,22.5\r\nQNET> ")
+	mock.SetResponse("?HVAC,10,2\r\n", "~HVAC,10,2,68,76\r\nQNET> ")
+	mock.SetResponse("#HVAC,10,2,66,78\r\n", "QNET> ")
+	mock.SetResponse("?HVAC,10,3\r\n", "~HVAC,10,3,4\r\nQNET> ")
+	mock.SetResponse("?HVAC,10,4\r\n", "~HVAC,10,4,1\r\nQNET> ")
+	mock.SetResponse("?HVAC,10,5\r\n", "~HVAC,10,5,2\r\nQNET> ")
+	mock.SetResponse("?HVAC,11,1\r\n", "~ERROR,2\r\nQNET> ")
+
+	mgr := &streamconn.SessionManager{}
+	s := mgr.New(mock, netutil.NewIdleTimer(10))
+	defer s.Release()
+
+	temp, err := protocol.GetTemperature(ctx, s, 10, protocol.Fahrenheit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := temp, 72.0; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	temp, err = protocol.GetTemperature(ctx, s, 10, protocol.Celsius)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := temp, 22.5; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if err := protocol.SetSetpoints(ctx, s, 10, protocol.Fahrenheit, 66, 78); err != nil {
+		t.Fatal(err)
+	}
+	heat, cool, err := protocol.GetSetpoints(ctx, s, 10, protocol.Fahrenheit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if heat != 68 || cool != 76 {
+		t.Errorf("got %v %v, want 68 76", heat, cool)
+	}
+	if err := protocol.SetSetpoints(ctx, s, 10, protocol.Fahrenheit, 80, 70); err == nil {
+		t.Errorf("expected an error for heat > cool")
+	}
+
+	mode, err := protocol.GetOperatingMode(ctx, s, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := mode, protocol.HVACModeAuto; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	fan, err := protocol.GetFanMode(ctx, s, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fan, protocol.HVACFanAuto; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	eco, err := protocol.GetEcoMode(ctx, s, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !eco {
+		t.Errorf("expected eco mode to be on")
+	}
+}
+
+func TestHVACParsing(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want protocol.HVACMode
+	}{
+		{"off", protocol.HVACModeOff},
+		{"Heat", protocol.HVACModeHeat},
+		{"cool", protocol.HVACModeCool},
+		{"auto", protocol.HVACModeAuto},
+		{"em-heat", protocol.HVACModeEmHeat},
+	} {
+		m, err := protocol.ParseHVACMode(tc.in)
+		if err != nil {
+			t.Errorf("%v: %v", tc.in, err)
+		}
+		if got, want := m, tc.want; got != want {
+			t.Errorf("%v: got %v, want %v", tc.in, got, want)
+		}
+	}
+	if _, err := protocol.ParseHVACMode("dry"); err == nil {
+		t.Errorf("expected an error")
+	}
+	if _, err := protocol.ParseHVACFan("medium"); err != nil {
+		t.Error(err)
+	}
+	if got, want := protocol.Fahrenheit.Convert(212, protocol.Celsius), 100.0; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := protocol.Celsius.Convert(20, protocol.Fahrenheit), 68.0; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package protocol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cosnicolaou/automation/net/streamconn"
+)
+
+// IntegrationIDActions represents the actions supported by the
+// INTEGRATIONID command group.
+type IntegrationIDActions int
+
+const (
+	// IntegrationIDSerial is used to obtain the integration ID for
+	// a serial number.
+	IntegrationIDSerial IntegrationIDActions = 1
+	// IntegrationIDInfo is used to obtain the type and serial number
+	// for an integration ID.
+	IntegrationIDInfo IntegrationIDActions = 3
+)
+
+// IntegrationIDType represents the type of object that an integration
+// ID refers to, eg. OUTPUT or DEVICE.
+type IntegrationIDType string
+
+const (
+	IntegrationIDOutput IntegrationIDType = "OUTPUT"
+	IntegrationIDDevice IntegrationIDType = "DEVICE"
+	// Shade groups are not reported via INTEGRATIONID and are instead
+	// detected by Discover using a SHADEGRP query.
+	IntegrationIDShadeGroup IntegrationIDType = "SHADEGRP"
+)
+
+// IntegrationIDDetails represents the information returned for an
+// integration ID.
+type IntegrationIDDetails struct {
+	ID     int               `json:"id"`
+	Type   IntegrationIDType `json:"type"`
+	Serial string            `json:"serial,omitempty"`
+}
+
+// GetIntegrationID sends a '?INTEGRATIONID,3,<id>' command to the Lutron
+// system to determine the type and serial number of the object referred
+// to by id. The response is of the form
+// ~INTEGRATIONID,<id>,<type>[,<serial number>].
+func GetIntegrationID(ctx context.Context, s *streamconn.Session, id int) (IntegrationIDDetails, error) {
+	ids := strconv.Itoa(id)
+	pars := strconv.AppendInt(make([]byte, 0, 16), int64(IntegrationIDInfo), 10)
+	pars = append(pars, ',')
+	pars = append(pars, ids...)
+	cmd := NewCommand(IntegrationIDCommands, false, pars)
+	cmd.SetCustomResponse([]byte("~INTEGRATIONID," + ids + ","))
+	r, err := cmd.Call(ctx, s)
+	if err != nil {
+		return IntegrationIDDetails{}, fmt.Errorf("integration id %v: %w", id, err)
+	}
+	typ, serial, _ := strings.Cut(r, ",")
+	return IntegrationIDDetails{
+		ID:     id,
+		Type:   IntegrationIDType(strings.ToUpper(strings.TrimSpace(typ))),
+		Serial: strings.TrimSpace(serial),
+	}, nil
+}
+
+// GetIntegrationIDForSerial sends a '?INTEGRATIONID,1,<serial>' command to
+// the Lutron system to determine the integration ID assigned to the
+// device with the specified serial number. The response is of the form
+// ~INTEGRATIONID,1,<serial>,<id>.
+func GetIntegrationIDForSerial(ctx context.Context, s *streamconn.Session, serial string) (int, error) {
+	pars := strconv.AppendInt(make([]byte, 0, 32), int64(IntegrationIDSerial), 10)
+	pars = append(pars, ',')
+	pars = append(pars, serial...)
+	r, err := NewCommand(IntegrationIDCommands, false, pars).Call(ctx, s)
+	if err != nil {
+		return 0, fmt.Errorf("serial number %v: %w", serial, err)
+	}
+	id, err := strconv.Atoi(strings.TrimSpace(r))
+	if err != nil {
+		return 0, fmt.Errorf("serial number %v: unexpected response: %q", serial, r)
+	}
+	return id, nil
+}
+
+// Discover determines the type of object referred to by id. It returns
+// ErrAccessPointObjectDoesNotExist if id is not in use. Shade groups
+// are detected via a SHADEGRP query since they are not reported by
+// INTEGRATIONID.
+func Discover(ctx context.Context, s *streamconn.Session, id int) (IntegrationIDDetails, error) {
+	details, err := GetIntegrationID(ctx, s, id)
+	if err == nil {
+		return details, nil
+	}
+	if !errors.Is(err, ErrAccessPointObjectDoesNotExist) {
+		return details, err
+	}
+	if _, gerr := GetLevel(ctx, s, ShadeGroupCommands, id); gerr == nil {
+		return IntegrationIDDetails{ID: id, Type: IntegrationIDShadeGroup}, nil
+	}
+	return IntegrationIDDetails{}, err
+}
@@ -0,0 +1,67 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package protocol_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cosnicolaou/automation/net/netutil"
+	"github.com/cosnicolaou/automation/net/streamconn"
+	"github.com/cosnicolaou/lutron/internal/testutil"
+	"github.com/cosnicolaou/lutron/protocol"
+)
+
+func TestIntegrationID(t *testing.T) {
+	ctx := context.Background()
+
+	mock := testutil.NewMockTransport(testing.Verbose())
+	mock.SetResponse("?INTEGRATIONID,3,5\r\n", "~INTEGRATIONID,5,DEVICE,0x0123ABCD\r\nQNET> ")
+	mock.SetResponse("?INTEGRATIONID,3,6\r\n", "~INTEGRATIONID,6,OUTPUT\r\nQNET> ")
+	mock.SetResponse("?INTEGRATIONID,3,7\r\n", "~ERROR,2\r\nQNET> ")
+	mock.SetResponse("?INTEGRATIONID,3,8\r\n", "~ERROR,2\r\nQNET> ")
+	mock.SetResponse("?SHADEGRP,7,1\r\n", "~SHADEGRP,7,1,0.00\r\nQNET> ")
+	mock.SetResponse("?SHADEGRP,8,1\r\n", "~ERROR,2\r\nQNET> ")
+	mock.SetResponse("?INTEGRATIONID,1,0x0123ABCD\r\n", "~INTEGRATIONID,1,0x0123ABCD,5\r\nQNET> ")
+
+	mgr := &streamconn.SessionManager{}
+	s := mgr.New(mock, netutil.NewIdleTimer(10))
+	defer s.Release()
+
+	details, err := protocol.GetIntegrationID(ctx, s, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := details, (protocol.IntegrationIDDetails{ID: 5, Type: protocol.IntegrationIDDevice, Serial: "0x0123ABCD"}); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	id, err := protocol.GetIntegrationIDForSerial(ctx, s, "0x0123ABCD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := id, 5; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	for _, tc := range []struct {
+		id   int
+		want protocol.IntegrationIDDetails
+		err  error
+	}{
+		{5, protocol.IntegrationIDDetails{ID: 5, Type: protocol.IntegrationIDDevice, Serial: "0x0123ABCD"}, nil},
+		{6, protocol.IntegrationIDDetails{ID: 6, Type: protocol.IntegrationIDOutput}, nil},
+		{7, protocol.IntegrationIDDetails{ID: 7, Type: protocol.IntegrationIDShadeGroup}, nil},
+		{8, protocol.IntegrationIDDetails{}, protocol.ErrAccessPointObjectDoesNotExist},
+	} {
+		got, err := protocol.Discover(ctx, s, tc.id)
+		if !errors.Is(err, tc.err) {
+			t.Errorf("%v: got %v, want %v", tc.id, err, tc.err)
+		}
+		if got != tc.want {
+			t.Errorf("%v: got %v, want %v", tc.id, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package protocol
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/cosnicolaou/automation/net/streamconn"
+)
+
+// MonitoringType represents the types of monitoring output that can be
+// enabled or disabled via the MONITORING command.
+// See https://assets.lutron.com/a/documents/040249.pdf, page 12.
+type MonitoringType int
+
+const (
+	MonitorDiagnostic     MonitoringType = 1
+	MonitorEvent          MonitoringType = 2
+	MonitorButton         MonitoringType = 3
+	MonitorLED            MonitoringType = 4
+	MonitorZone           MonitoringType = 5
+	MonitorOccupancy      MonitoringType = 6
+	MonitorPhotosensor    MonitoringType = 7
+	MonitorScene          MonitoringType = 8
+	MonitorSysvar         MonitoringType = 10
+	MonitorReply          MonitoringType = 11
+	MonitorPrompt         MonitoringType = 12
+	MonitorDeviceLock     MonitoringType = 13
+	MonitorSequence       MonitoringType = 14
+	MonitorHVAC           MonitoringType = 15
+	MonitorMode           MonitoringType = 16
+	MonitorPresetTransfer MonitoringType = 17
+	MonitorL1Runtime      MonitoringType = 18
+	MonitorL2Runtime      MonitoringType = 19
+	MonitorDRC            MonitoringType = 20
+	// MonitorAll refers to all monitoring types except for Reply and Prompt.
+	MonitorAll MonitoringType = 255
+)
+
+var monitoringNames = map[MonitoringType]string{
+	MonitorDiagnostic:     "diagnostic",
+	MonitorEvent:          "event",
+	MonitorButton:         "button",
+	MonitorLED:            "led",
+	MonitorZone:           "zone",
+	MonitorOccupancy:      "occupancy",
+	MonitorPhotosensor:    "photosensor",
+	MonitorScene:          "scene",
+	MonitorSysvar:         "sysvar",
+	MonitorReply:          "reply",
+	MonitorPrompt:         "prompt",
+	MonitorDeviceLock:     "device-lock",
+	MonitorSequence:       "sequence",
+	MonitorHVAC:           "hvac",
+	MonitorMode:           "mode",
+	MonitorPresetTransfer: "preset-transfer",
+	MonitorL1Runtime:      "l1-runtime",
+	MonitorL2Runtime:      "l2-runtime",
+	MonitorDRC:            "drc",
+	MonitorAll:            "all",
+}
+
+// MonitoringTypes returns all of the supported monitoring types, excluding
+// MonitorAll, in numeric order.
+func MonitoringTypes() []MonitoringType {
+	types := make([]MonitoringType, 0, len(monitoringNames))
+	for t := range monitoringNames {
+		if t != MonitorAll {
+			types = append(types, t)
+		}
+	}
+	slices.Sort(types)
+	return types
+}
+
+func (m MonitoringType) String() string {
+	if n, ok := monitoringNames[m]; ok {
+		return n
+	}
+	return fmt.Sprintf("unknown-monitoring(%d)", int(m))
+}
+
+// ParseMonitoringType parses the name of a monitoring type, eg. button,
+// zone, sysvar etc.
+func ParseMonitoringType(m string) (MonitoringType, error) {
+	for t, n := range monitoringNames {
+		if strings.EqualFold(m, n) {
+			return t, nil
+		}
+	}
+	return 0, fmt.Errorf("unsupported monitoring type: %q", m)
+}
+
+// ParseMonitoringTypes parses a list of monitoring type names.
+func ParseMonitoringTypes(names []string) ([]MonitoringType, error) {
+	types := make([]MonitoringType, 0, len(names))
+	for _, n := range names {
+		t, err := ParseMonitoringType(n)
+		if err != nil {
+			return nil, err
+		}
+		types = append(types, t)
+	}
+	return types, nil
+}
+
+func monitoringParameters(typ MonitoringType) []byte {
+	return strconv.AppendInt(make([]byte, 0, 8), int64(typ), 10)
+}
+
+// SetMonitoring enables or disables the specified type of monitoring
+// for the current connection.
+func SetMonitoring(ctx context.Context, s *streamconn.Session, typ MonitoringType, enable bool) error {
+	pars := monitoringParameters(typ)
+	if enable {
+		pars = append(pars, ',', '1')
+	} else {
+		pars = append(pars, ',', '2')
+	}
+	if err := NewCommand(MonitorCommands, true, pars).Invoke(ctx, s); err != nil {
+		return fmt.Errorf("monitoring %v: %w", typ, err)
+	}
+	return nil
+}
+
+// GetMonitoring returns true if the specified type of monitoring is
+// enabled for the current connection.
+func GetMonitoring(ctx context.Context, s *streamconn.Session, typ MonitoringType) (bool, error) {
+	r, err := NewCommand(MonitorCommands, false, monitoringParameters(typ)).Call(ctx, s)
+	if err != nil {
+		return false, fmt.Errorf("monitoring %v: %w", typ, err)
+	}
+	switch r {
+	case "1":
+		return true, nil
+	case "2":
+		return false, nil
+	}
+	return false, fmt.Errorf("monitoring %v: unexpected response: %q", typ, r)
+}
+
+// ConfigureMonitoring disables all monitoring, other than replies and
+// prompts which are required for commands to be processed, and then
+// enables the specified types of monitoring.
+func ConfigureMonitoring(ctx context.Context, s *streamconn.Session, enabled ...MonitoringType) error {
+	if err := SetMonitoring(ctx, s, MonitorAll, false); err != nil {
+		return err
+	}
+	for _, typ := range enabled {
+		if err := SetMonitoring(ctx, s, typ, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
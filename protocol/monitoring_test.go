@@ -0,0 +1,61 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package protocol_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cosnicolaou/automation/net/netutil"
+	"github.com/cosnicolaou/automation/net/streamconn"
+	"github.com/cosnicolaou/lutron/internal/testutil"
+	"github.com/cosnicolaou/lutron/protocol"
+)
+
+func TestMonitoring(t *testing.T) {
+	ctx := context.Background()
+
+	mock := testutil.NewMockTransport(testing.Verbose())
+	mock.SetResponse("#MONITORING,255,2\r\n", "~MONITORING,255,2\r\nQNET> ")
+	mock.SetResponse("#MONITORING,3,1\r\n", "~MONITORING,3,1\r\nQNET> ")
+	mock.SetResponse("#MONITORING,10,1\r\n", "~MONITORING,10,1\r\nQNET> ")
+	mock.SetResponse("?MONITORING,3\r\n", "~MONITORING,3,1\r\nQNET> ")
+	mock.SetResponse("?MONITORING,5\r\n", "~MONITORING,5,2\r\nQNET> ")
+
+	mgr := &streamconn.SessionManager{}
+	s := mgr.New(mock, netutil.NewIdleTimer(10))
+	defer s.Release()
+
+	types, err := protocol.ParseMonitoringTypes([]string{"button", "SysVar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := protocol.ConfigureMonitoring(ctx, s, types...); err != nil {
+		t.Fatal(err)
+	}
+	on, err := protocol.GetMonitoring(ctx, s, protocol.MonitorButton)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !on {
+		t.Errorf("button monitoring should be enabled")
+	}
+	on, err = protocol.GetMonitoring(ctx, s, protocol.MonitorZone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if on {
+		t.Errorf("zone monitoring should be disabled")
+	}
+
+	if _, err := protocol.ParseMonitoringType("nonsense"); err == nil {
+		t.Errorf("expected an error")
+	}
+	for _, typ := range protocol.MonitoringTypes() {
+		if got, err := protocol.ParseMonitoringType(typ.String()); err != nil || got != typ {
+			t.Errorf("%v: got %v, %v", typ, got, err)
+		}
+	}
+}
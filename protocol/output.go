@@ -0,0 +1,91 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package protocol
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cosnicolaou/automation/net/streamconn"
+)
+
+// OutputActions represents the actions supported by the OUTPUT and SHADEGRP
+// command groups.
+type OutputActions int
+
+const (
+	OutputLevel OutputActions = iota + 1
+	OutputRaise
+	OutputLower
+	OutputStop
+)
+
+// FormatDuration formats a fade or delay time using the formats
+// accepted by QS systems, ie. SS.ss, MM:SS or HH:MM:SS.
+func FormatDuration(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	if d < time.Minute {
+		return strconv.FormatFloat(math.Round(d.Seconds()*100)/100, 'f', -1, 64)
+	}
+	d = d.Round(time.Second)
+	h, m, s := int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60
+	if h == 0 {
+		return fmt.Sprintf("%02d:%02d", m, s)
+	}
+	return fmt.Sprintf("%02d:%02d:%02d", h, m, s)
+}
+
+// FormatLevel formats a level in the range 0..100 with at most two
+// decimal places.
+func FormatLevel(level float64) string {
+	return strconv.FormatFloat(math.Round(level*100)/100, 'f', -1, 64)
+}
+
+// SetLevel sends a '#<group>,<id>,1,<level>[,<fade>[,<delay>]]' command to
+// the Lutron system. The fade and delay are only sent if non-zero.
+func SetLevel(ctx context.Context, s *streamconn.Session, cg CommandGroup, id int, level float64, fade, delay time.Duration) error {
+	if level < 0 || level > 100 {
+		return fmt.Errorf("level %v must be in the range 0..100", level)
+	}
+	pars := make([]byte, 0, 32)
+	pars = strconv.AppendInt(pars, int64(id), 10)
+	pars = append(pars, ',', '1', ',')
+	pars = append(pars, FormatLevel(level)...)
+	if fade > 0 || delay > 0 {
+		pars = append(pars, ',')
+		pars = append(pars, FormatDuration(fade)...)
+	}
+	if delay > 0 {
+		pars = append(pars, ',')
+		pars = append(pars, FormatDuration(delay)...)
+	}
+	return NewCommand(cg, true, pars).Invoke(ctx, s)
+}
+
+// GetLevel sends a '?<group>,<id>,1' command to the Lutron system and
+// returns the reported level.
+func GetLevel(ctx context.Context, s *streamconn.Session, cg CommandGroup, id int) (float64, error) {
+	pars := strconv.AppendInt(make([]byte, 0, 16), int64(id), 10)
+	pars = append(pars, ',', '1')
+	r, err := NewCommand(cg, false, pars).Call(ctx, s)
+	if err != nil {
+		return 0, fmt.Errorf("id %v: %w", id, err)
+	}
+	// Some systems append fade/delay fields to the level.
+	if idx := strings.IndexByte(r, ','); idx >= 0 {
+		r = r[:idx]
+	}
+	level, err := strconv.ParseFloat(r, 64)
+	if err != nil {
+		return 0, fmt.Errorf("id %v: failed to parse level: %q: %w", id, r, err)
+	}
+	return level, nil
+}
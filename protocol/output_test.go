@@ -0,0 +1,71 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package protocol_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cosnicolaou/automation/net/netutil"
+	"github.com/cosnicolaou/automation/net/streamconn"
+	"github.com/cosnicolaou/lutron/internal/testutil"
+	"github.com/cosnicolaou/lutron/protocol"
+)
+
+func TestFormatDuration(t *testing.T) {
+	for _, tc := range []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "0"},
+		{time.Second, "1"},
+		{1500 * time.Millisecond, "1.5"},
+		{90 * time.Second, "01:30"},
+		{time.Hour + 2*time.Minute + 3*time.Second, "01:02:03"},
+	} {
+		if got, want := protocol.FormatDuration(tc.d), tc.want; got != want {
+			t.Errorf("%v: got %v, want %v", tc.d, got, want)
+		}
+	}
+}
+
+func TestOutputLevel(t *testing.T) {
+	ctx := context.Background()
+
+	mock := testutil.NewMockTransport(testing.Verbose())
+	mock.SetResponse("#OUTPUT,12,1,50\r\n", "QNET> ")
+	mock.SetResponse("#SHADEGRP,3,1,75.5,2,01:30\r\n", "QNET> ")
+	mock.SetResponse("?OUTPUT,12,1\r\n", "~OUTPUT,12,1,50.00\r\nQNET> ")
+	mock.SetResponse("?SHADEGRP,3,1\r\n", "~SHADEGRP,3,1,75.50,0.00,0.00\r\nQNET> ")
+
+	mgr := &streamconn.SessionManager{}
+	s := mgr.New(mock, netutil.NewIdleTimer(10))
+	defer s.Release()
+
+	if err := protocol.SetLevel(ctx, s, protocol.OutputCommands, 12, 50, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := protocol.SetLevel(ctx, s, protocol.ShadeGroupCommands, 3, 75.5, 2*time.Second, 90*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := protocol.SetLevel(ctx, s, protocol.OutputCommands, 12, 101, 0, 0); err == nil {
+		t.Errorf("expected an error for an out of range level")
+	}
+	level, err := protocol.GetLevel(ctx, s, protocol.OutputCommands, 12)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := level, 50.0; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	level, err = protocol.GetLevel(ctx, s, protocol.ShadeGroupCommands, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := level, 75.5; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
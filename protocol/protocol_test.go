@@ -116,3 +116,42 @@ func TestParseDateTime(t *testing.T) {
 		t.Errorf("got %v, want %v", st, time.Date(2024, 11, 17, 20, 21, 47, 0, time.FixedZone("PST", -8*60*60)))
 	}
 }
+
+func TestParseResponseUnrelatedErrors(t *testing.T) {
+	// An error that precedes the reply, or is followed by other output,
+	// belongs to another request.
+	for i, tc := range []struct {
+		cmd  string
+		resp string
+		want string
+	}{
+		{"~OUTPUT,3,1,", "\x00~ERROR,2\r\n~OUTPUT,3,1,50.00\r\nQNET> ", "50.00"},
+		{"~OUTPUT,3,1,", "~ERROR,2\r\n~OUTPUT,450,29,6\r\nQNET> ", ""},
+	} {
+		got, err := protocol.ParseResponse([]byte(tc.cmd), []byte(tc.resp))
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", i, err)
+		}
+		if got != tc.want {
+			t.Errorf("%v: got %q, want %q", i, got, tc.want)
+		}
+	}
+}
+
+func TestParseResponseErrors(t *testing.T) {
+	for i, tc := range []struct {
+		cmd  string
+		resp string
+		err  error
+	}{
+		{"~OUTPUT,999,1,", "~ERROR,2\r\nQNET> ", protocol.ErrAccessPointObjectDoesNotExist},
+		{"~OUTPUT,999,1,", "~OUTPUT,450,29,6\r\n~ERROR,2\r\nQNET> ", protocol.ErrAccessPointObjectDoesNotExist},
+		{"~SYSTEM,7,", "~ERROR,6", protocol.ErrAccessPointUnsupportedCommand},
+		{"~OUTPUT,3,1,", "\x00~ERROR,2\r\n~OUTPUT,450,29,6\r\n~ERROR,6\r\nQNET> ", protocol.ErrAccessPointUnsupportedCommand},
+	} {
+		_, err := protocol.ParseResponse([]byte(tc.cmd), []byte(tc.resp))
+		if !errors.Is(err, tc.err) {
+			t.Errorf("%v: got %v, want %v", i, err, tc.err)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package protocol
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimit represents the configuration for a RateLimiter.
+type RateLimit struct {
+	// Rate is the sustained number of commands per second, zero
+	// means no limit.
+	Rate float64 `yaml:"rate"`
+	// Burst is the number of commands that may be sent back-to-back
+	// before Rate is enforced, it defaults to 1.
+	Burst int `yaml:"burst"`
+	// MinGap is the minimum time between successive commands for the
+	// same integration ID.
+	MinGap time.Duration `yaml:"min_gap"`
+}
+
+// RateLimiter limits the rate at which commands are sent to a processor
+// using a token bucket for all commands and a minimum gap between
+// commands for the same integration ID.
+type RateLimiter struct {
+	mu     sync.Mutex
+	cfg    RateLimit
+	tokens float64
+	last   time.Time
+	nextID map[int]time.Time
+	now    func() time.Time
+}
+
+// NewRateLimiter returns a new RateLimiter with the specified configuration.
+func NewRateLimiter(cfg RateLimit) *RateLimiter {
+	if cfg.Burst <= 0 {
+		cfg.Burst = 1
+	}
+	return &RateLimiter{
+		cfg:    cfg,
+		tokens: float64(cfg.Burst),
+		nextID: map[int]time.Time{},
+		now:    time.Now,
+	}
+}
+
+// reservation records the state changed by reserve so that it can be
+// refunded if the command is never sent.
+type reservation struct {
+	delay    time.Duration
+	id       int
+	token    bool
+	prevNext time.Time
+	next     time.Time
+}
+
+// reserve reserves a slot for a command for the specified integration ID,
+// or for no specific ID if id is zero, and a token from the bucket if
+// token is true. The returned reservation records how long the caller
+// must wait before sending the command.
+func (l *RateLimiter) reserve(id int, token bool) reservation {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	r := reservation{id: id}
+	if token && l.cfg.Rate > 0 {
+		if !l.last.IsZero() {
+			l.tokens += now.Sub(l.last).Seconds() * l.cfg.Rate
+			l.tokens = min(l.tokens, float64(l.cfg.Burst))
+		}
+		l.last = now
+		l.tokens--
+		r.token = true
+		if l.tokens < 0 {
+			r.delay = time.Duration(-l.tokens / l.cfg.Rate * float64(time.Second))
+		}
+	}
+	if id > 0 && l.cfg.MinGap > 0 {
+		r.prevNext = l.nextID[id]
+		if r.prevNext.After(now.Add(r.delay)) {
+			r.delay = r.prevNext.Sub(now)
+		}
+		r.next = now.Add(r.delay + l.cfg.MinGap)
+		l.nextID[id] = r.next
+	}
+	return r
+}
+
+// cancel returns the token and per-ID slot taken by r. The per-ID slot
+// is only restored if no later reservation has been made for that ID.
+func (l *RateLimiter) cancel(r reservation) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if r.token {
+		l.tokens = min(l.tokens+1, float64(l.cfg.Burst))
+	}
+	if !r.next.IsZero() && l.nextID[r.id].Equal(r.next) {
+		if r.prevNext.IsZero() {
+			delete(l.nextID, r.id)
+		} else {
+			l.nextID[r.id] = r.prevNext
+		}
+	}
+}
+
+// Wait blocks until a command for the specified integration ID, or for
+// no specific ID if id is zero, may be sent or the context is canceled.
+// The reservation is refunded if the context is canceled first.
+func (l *RateLimiter) Wait(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return l.wait(ctx, l.reserve(id, true))
+}
+
+// wait waits for the delay required by r, refunding r if the context
+// is canceled first.
+func (l *RateLimiter) wait(ctx context.Context, r reservation) error {
+	if r.delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(r.delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.cancel(r)
+		return ctx.Err()
+	}
+}
+
+type rateLimiterKey struct{}
+
+// WithRateLimiter returns a context that carries the supplied RateLimiter,
+// all commands issued using that context will wait for the limiter before
+// being sent. A nil RateLimiter disables rate limiting for the returned
+// context.
+func WithRateLimiter(ctx context.Context, l *RateLimiter) context.Context {
+	return context.WithValue(ctx, rateLimiterKey{}, l)
+}
+
+func rateLimiterFromContext(ctx context.Context) *RateLimiter {
+	l, _ := ctx.Value(rateLimiterKey{}).(*RateLimiter)
+	return l
+}
+
+// idCommandGroups are the command groups whose first parameter
+// is an integration ID.
+var idCommandGroups = [][]byte{
+	[]byte("OUTPUT,"),
+	[]byte("DEVICE,"),
+	[]byte("SHADEGRP,"),
+	[]byte("HVAC,"),
+	[]byte("GROUP,"),
+	[]byte("SYSVAR,"),
+}
+
+// requestIntegrationID returns the integration ID that the request, of the
+// form [?#]<GROUP>,<id>,..., refers to or zero if it does not refer to one.
+func requestIntegrationID(req []byte) int {
+	if len(req) < 2 {
+		return 0
+	}
+	req = req[1:]
+	for _, grp := range idCommandGroups {
+		if !bytes.HasPrefix(req, grp) {
+			continue
+		}
+		pars := req[len(grp):]
+		if i := bytes.IndexAny(pars, ",\r\n"); i >= 0 {
+			pars = pars[:i]
+		}
+		id, _ := strconv.Atoi(string(pars))
+		return id
+	}
+	return 0
+}
+
+// RawIntegrationID returns the integration ID that the command line, of
+// the form accepted by Raw, refers to or zero if it does not refer to one.
+func RawIntegrationID(line string) int {
+	return requestIntegrationID([]byte(line))
+}
+
+type reservedIDsKey struct{}
+
+// reservedIDs records the number of commands for each integration ID
+// whose minimum gap has already been waited for.
+type reservedIDs struct {
+	mu sync.Mutex
+	n  map[int]int
+}
+
+func (r *reservedIDs) take(id int) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.n[id] == 0 {
+		return false
+	}
+	r.n[id]--
+	return true
+}
+
+// ReserveIntegrationIDs waits for the minimum gap, as configured for the
+// rate limiter in the context, for one command for each of the specified
+// integration IDs. It is intended to be called before a session is
+// acquired so that waiting for one ID does not hold up commands for other
+// IDs that would otherwise be blocked waiting for the session. The
+// returned context records the reservations so that the first command sent
+// for each ID only waits for the limiter's token bucket. An ID may be
+// specified multiple times to reserve a slot for multiple commands. Any
+// reservations already made are refunded if the context is canceled.
+func ReserveIntegrationIDs(ctx context.Context, ids ...int) (context.Context, error) {
+	if err := ctx.Err(); err != nil {
+		return ctx, err
+	}
+	l := rateLimiterFromContext(ctx)
+	if l == nil || len(ids) == 0 {
+		return ctx, nil
+	}
+	reserved := &reservedIDs{n: map[int]int{}}
+	done := make([]reservation, 0, len(ids))
+	for _, id := range ids {
+		if id <= 0 {
+			continue
+		}
+		r := l.reserve(id, false)
+		if err := l.wait(ctx, r); err != nil {
+			for _, r := range done {
+				l.cancel(r)
+			}
+			return ctx, err
+		}
+		done = append(done, r)
+		reserved.n[id]++
+	}
+	return context.WithValue(ctx, reservedIDsKey{}, reserved), nil
+}
+
+// WaitForRateLimit waits for the rate limiter, if any, in the context
+// before a command for the specified integration ID is sent. It is
+// intended for callers that must send a sequence of commands without
+// interruption, and hence send them using a context that carries
+// no rate limiter, once the first may be sent.
+func WaitForRateLimit(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return waitForID(ctx, id)
+}
+
+// waitForRateLimit waits for the rate limiter, if any, in the context
+// before the supplied request is sent.
+func waitForRateLimit(ctx context.Context, req []byte) error {
+	return waitForID(ctx, requestIntegrationID(req))
+}
+
+func waitForID(ctx context.Context, id int) error {
+	l := rateLimiterFromContext(ctx)
+	if l == nil {
+		return nil
+	}
+	if r, ok := ctx.Value(reservedIDsKey{}).(*reservedIDs); ok && id > 0 && r.take(id) {
+		// The minimum gap for this command has already been waited for.
+		id = 0
+	}
+	return l.Wait(ctx, id)
+}
@@ -0,0 +1,164 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package protocol
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRequestIntegrationID(t *testing.T) {
+	for _, tc := range []struct {
+		req string
+		id  int
+	}{
+		{"#OUTPUT,5,1,50\r\n", 5},
+		{"?SHADEGRP,12,1\r\n", 12},
+		{"?DEVICE,100,3,9\r\n", 100},
+		{"?HVAC,7\r\n", 7},
+		{"?SYSTEM,1\r\n", 0},
+		{"#MONITORING,255,2\r\n", 0},
+		{"?INTEGRATIONID,3,5\r\n", 0},
+		{"", 0},
+	} {
+		if got, want := requestIntegrationID([]byte(tc.req)), tc.id; got != want {
+			t.Errorf("%q: got %v, want %v", tc.req, got, want)
+		}
+	}
+}
+
+func TestRateLimiterReserve(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewRateLimiter(RateLimit{Rate: 10, Burst: 2, MinGap: time.Second})
+	l.now = func() time.Time { return now }
+
+	for i, tc := range []struct {
+		advance time.Duration
+		id      int
+		delay   time.Duration
+	}{
+		{0, 1, 0},                      // burst.
+		{0, 2, 0},                      // burst.
+		{0, 0, 100 * time.Millisecond}, // bucket empty.
+		{0, 0, 200 * time.Millisecond}, // and again.
+		{time.Second, 3, 0},            // bucket refilled.
+		{0, 3, time.Second},            // minimum gap for id 3.
+		{500 * time.Millisecond, 4, 0}, // different id.
+		{200 * time.Millisecond, 3, 1300 * time.Millisecond}, // gap after the previously reserved command for id 3.
+	} {
+		now = now.Add(tc.advance)
+		if got, want := l.reserve(tc.id, true).delay, tc.delay; got != want {
+			t.Errorf("%v: got %v, want %v", i, got, want)
+		}
+	}
+
+	unlimited := NewRateLimiter(RateLimit{})
+	for range 10 {
+		if got := unlimited.reserve(1, true).delay; got != 0 {
+			t.Errorf("unexpected delay: %v", got)
+		}
+	}
+}
+
+func TestRateLimiterCancel(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewRateLimiter(RateLimit{Rate: 10, MinGap: time.Second})
+	l.now = func() time.Time { return now }
+
+	if got := l.reserve(1, true).delay; got != 0 {
+		t.Fatalf("unexpected delay: %v", got)
+	}
+	// Canceled reservations must not delay subsequent commands.
+	for range 3 {
+		l.cancel(l.reserve(1, true))
+	}
+	if got, want := l.reserve(1, true).delay, time.Second; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := l.reserve(2, true).delay, 200*time.Millisecond; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// The per-ID slot is not restored if a later reservation was made.
+	r := l.reserve(3, true)
+	later := l.reserve(3, true)
+	l.cancel(r)
+	if got, want := l.nextID[3], later.next; !got.Equal(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	ctx := context.Background()
+	l := NewRateLimiter(RateLimit{Rate: 50})
+	start := time.Now()
+	for range 3 {
+		if err := l.Wait(ctx, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if took := time.Since(start); took < 35*time.Millisecond {
+		t.Errorf("rate limit not enforced: %v", took)
+	}
+
+	l = NewRateLimiter(RateLimit{Rate: 0.1})
+	if err := l.Wait(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	if err := l.Wait(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if took := time.Since(start); took > 5*time.Second {
+		t.Errorf("cancellation not honoured: %v", took)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.tokens < -0.5 {
+		t.Errorf("token not refunded: %v", l.tokens)
+	}
+}
+
+func TestReserveIntegrationIDs(t *testing.T) {
+	l := NewRateLimiter(RateLimit{MinGap: time.Hour})
+	ctx := WithRateLimiter(context.Background(), l)
+
+	rctx, err := ReserveIntegrationIDs(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The first command for a reserved id does not wait for the gap
+	// again, subsequent ones do.
+	if err := waitForRateLimit(rctx, []byte("#OUTPUT,1,1,50\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	tctx, cancel := context.WithTimeout(rctx, 10*time.Millisecond)
+	defer cancel()
+	if err := waitForRateLimit(tctx, []byte("#OUTPUT,1,1,50\r\n")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// Reservations are refunded if a later id cannot be reserved.
+	tctx, cancel = context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := ReserveIntegrationIDs(tctx, 2, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	l.mu.Lock()
+	_, ok := l.nextID[2]
+	l.mu.Unlock()
+	if ok {
+		t.Errorf("reservation for id 2 was not refunded")
+	}
+
+	// No limiter, no waiting.
+	if _, err := ReserveIntegrationIDs(context.Background(), 1, 1); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package protocol_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cosnicolaou/automation/net/netutil"
+	"github.com/cosnicolaou/automation/net/streamconn"
+	"github.com/cosnicolaou/lutron/internal/testutil"
+	"github.com/cosnicolaou/lutron/protocol"
+)
+
+func TestReplayGetTime(t *testing.T) {
+	ctx := context.Background()
+	replay, err := testutil.NewReplayTransportFromFile(filepath.Join("testdata", "gettime.transcript"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := &streamconn.SessionManager{}
+	s := mgr.New(replay, netutil.NewIdleTimer(10))
+	defer s.Release()
+
+	if err := protocol.QSLogin(ctx, s, "admin", "password"); err != nil {
+		t.Fatal(err)
+	}
+	st, err := protocol.GetTime(ctx, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2024, 11, 17, 20, 21, 47, 0, time.FixedZone("PST", -8*60*60)); !st.Equal(want) {
+		t.Errorf("got %v, want %v", st, want)
+	}
+	if got, want := replay.Remaining(), 0; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
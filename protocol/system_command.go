@@ -28,19 +28,58 @@ const (
 )
 
 // NormalizeTimeZone normalizes a timezone string to the form: (+|-)HH:MM
-// given an input of (+|-)H:MM as returned by QS systems.
+// given an input of (+|-)H:MM as returned by QS systems. A missing sign
+// is treated as positive and an empty string as UTC. Strings that cannot
+// be interpreted are returned unchanged.
 func NormalizeTimeZone(tz string) string {
-	switch len(tz) {
-	case 0:
-		return "0000"
-	case 6:
+	tz = strings.TrimSpace(tz)
+	if len(tz) == 0 {
+		return "+00:00"
+	}
+	sign := "+"
+	rest := tz
+	switch tz[0] {
+	case '+', '-':
+		sign, rest = tz[:1], tz[1:]
+	}
+	h, m, ok := strings.Cut(rest, ":")
+	if !ok {
+		// Allow for HHMM and H formats.
+		switch len(rest) {
+		case 1, 2:
+			h, m = rest, "00"
+		case 4:
+			h, m = rest[:2], rest[2:]
+		default:
+			return tz
+		}
+	}
+	if len(h) == 0 || len(h) > 2 || len(m) != 2 || !isDigits(h) || !isDigits(m) {
 		return tz
-	case 5:
-		mm := tz[3:]
-		h := tz[1:2]
-		return tz[0:1] + "0" + h + ":" + mm
 	}
-	return tz
+	if len(h) == 1 {
+		h = "0" + h
+	}
+	return sign + h + ":" + m
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseTimeZone parses a timezone as returned by QS systems and returns
+// the corresponding fixed offset location.
+func ParseTimeZone(tz string) (*time.Location, error) {
+	t, err := time.Parse("-07:00", NormalizeTimeZone(tz))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse timezone: %q: %w", tz, err)
+	}
+	return t.Location(), nil
 }
 
 // System sends a '[#?]System' command to the Lutron system.
@@ -67,6 +106,8 @@ func SystemQuery(ctx context.Context, s *streamconn.Session, action SystemAction
 	return response, nil
 }
 
+// GetTime returns the processor's current date and time in the
+// processor's timezone.
 func GetTime(ctx context.Context, s *streamconn.Session) (time.Time, error) {
 	date, err := SystemQuery(ctx, s, SystemDate)
 	if err != nil {
@@ -76,16 +117,29 @@ func GetTime(ctx context.Context, s *streamconn.Session) (time.Time, error) {
 	if err != nil {
 		return time.Time{}, err
 	}
-	tz, err := SystemQuery(ctx, s, SystemTimeZone)
+	loc, err := GetTimeZone(ctx, s)
 	if err != nil {
 		return time.Time{}, err
 	}
-	tzn := NormalizeTimeZone(tz)
-	sysTime, err := time.Parse("01/02/2006 15:04:05 -07:00", date+" "+tod+" "+tzn)
+	return parseDateTime(date, tod, loc)
+}
+
+func parseDateTime(date, tod string, loc *time.Location) (time.Time, error) {
+	t, err := time.ParseInLocation("01/02/2006 15:04:05", date+" "+tod, loc)
 	if err != nil {
 		return time.Time{}, err
 	}
-	return sysTime, nil
+	return t, nil
+}
+
+// GetTimeZone returns the processor's timezone as a fixed offset
+// from UTC.
+func GetTimeZone(ctx context.Context, s *streamconn.Session) (*time.Location, error) {
+	tz, err := SystemQuery(ctx, s, SystemTimeZone)
+	if err != nil {
+		return nil, err
+	}
+	return ParseTimeZone(tz)
 }
 
 func GetLatLong(ctx context.Context, s *streamconn.Session) (float64, float64, error) {
@@ -108,7 +162,21 @@ func GetLatLong(ctx context.Context, s *streamconn.Session) (float64, float64, e
 	return lat, long, nil
 }
 
+// GetSunriseSunset returns the sunrise and sunset times for the processor's
+// current date in the processor's timezone.
 func GetSunriseSunset(ctx context.Context, s *streamconn.Session) (time.Time, time.Time, error) {
+	date, err := SystemQuery(ctx, s, SystemDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	loc, err := GetTimeZone(ctx, s)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return getSunriseSunset(ctx, s, date, loc)
+}
+
+func getSunriseSunset(ctx context.Context, s *streamconn.Session, date string, loc *time.Location) (time.Time, time.Time, error) {
 	sunrise, err := SystemQuery(ctx, s, SystemSunrise)
 	if err != nil {
 		return time.Time{}, time.Time{}, err
@@ -117,12 +185,11 @@ func GetSunriseSunset(ctx context.Context, s *streamconn.Session) (time.Time, ti
 	if err != nil {
 		return time.Time{}, time.Time{}, err
 	}
-
-	sunriseT, err := time.Parse("15:04:05", sunrise)
+	sunriseT, err := parseDateTime(date, sunrise, loc)
 	if err != nil {
 		return time.Time{}, time.Time{}, err
 	}
-	sunsetT, err := time.Parse("15:04:05", sunset)
+	sunsetT, err := parseDateTime(date, sunset, loc)
 	if err != nil {
 		return time.Time{}, time.Time{}, err
 	}
@@ -134,5 +201,102 @@ func GetVersion(ctx context.Context, s *streamconn.Session) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	return data, nil
+	return strings.TrimSpace(data), nil
+}
+
+// SystemInfo represents the time, location and version information
+// reported by a QS processor.
+type SystemInfo struct {
+	Time       time.Time      `json:"time"`
+	TimeZone   *time.Location `json:"-"`
+	Latitude   float64        `json:"latitude"`
+	Longitude  float64        `json:"longitude"`
+	Sunrise    time.Time      `json:"sunrise"`
+	Sunset     time.Time      `json:"sunset"`
+	OSRevision string         `json:"os_revision"`
+}
+
+// GetSystemInfo returns the processor's time, timezone, location,
+// sunrise and sunset times and OS revision.
+func GetSystemInfo(ctx context.Context, s *streamconn.Session) (SystemInfo, error) {
+	var info SystemInfo
+	date, err := SystemQuery(ctx, s, SystemDate)
+	if err != nil {
+		return info, err
+	}
+	tod, err := SystemQuery(ctx, s, SystemTime)
+	if err != nil {
+		return info, err
+	}
+	if info.TimeZone, err = GetTimeZone(ctx, s); err != nil {
+		return info, err
+	}
+	if info.Time, err = parseDateTime(date, tod, info.TimeZone); err != nil {
+		return info, err
+	}
+	if info.Latitude, info.Longitude, err = GetLatLong(ctx, s); err != nil {
+		return info, err
+	}
+	if info.Sunrise, info.Sunset, err = getSunriseSunset(ctx, s, date, info.TimeZone); err != nil {
+		return info, err
+	}
+	if info.OSRevision, err = GetVersion(ctx, s); err != nil {
+		return info, err
+	}
+	return info, nil
+}
+
+// SystemSet sends a '#System' command with the supplied parameters.
+func SystemSet(ctx context.Context, s *streamconn.Session, action SystemActions, parameters ...string) error {
+	pars := strconv.AppendInt(make([]byte, 0, 32), int64(action), 10)
+	for _, p := range parameters {
+		pars = append(pars, ',')
+		pars = append(pars, p...)
+	}
+	if err := NewCommand(SystemCommands, true, pars).Invoke(ctx, s); err != nil {
+		return fmt.Errorf("%v: %w", action, err)
+	}
+	return nil
+}
+
+// SetTime sets the processor's time of day to that of t. Note that
+// t should be expressed in the processor's timezone.
+func SetTime(ctx context.Context, s *streamconn.Session, t time.Time) error {
+	return SystemSet(ctx, s, SystemTime, t.Format("15:04:05"))
+}
+
+// SetDate sets the processor's date to that of t. Note that t should
+// be expressed in the processor's timezone.
+func SetDate(ctx context.Context, s *streamconn.Session, t time.Time) error {
+	return SystemSet(ctx, s, SystemDate, t.Format("01/02/2006"))
+}
+
+// FormatTimeZone formats a timezone offset as (+|-)H:MM as used by QS
+// systems.
+func FormatTimeZone(offset time.Duration) string {
+	sign := "+"
+	if offset < 0 {
+		sign = "-"
+		offset = -offset
+	}
+	offset = offset.Round(time.Minute)
+	return fmt.Sprintf("%v%d:%02d", sign, int(offset.Hours()), int(offset.Minutes())%60)
+}
+
+// SetTimeZone sets the processor's timezone as an offset from UTC.
+func SetTimeZone(ctx context.Context, s *streamconn.Session, offset time.Duration) error {
+	return SystemSet(ctx, s, SystemTimeZone, FormatTimeZone(offset))
+}
+
+// SetLatLong sets the processor's location.
+func SetLatLong(ctx context.Context, s *streamconn.Session, lat, long float64) error {
+	if lat < -90 || lat > 90 {
+		return fmt.Errorf("latitude %v must be in the range -90..90", lat)
+	}
+	if long < -180 || long > 180 {
+		return fmt.Errorf("longitude %v must be in the range -180..180", long)
+	}
+	return SystemSet(ctx, s, SystemLatLong,
+		strconv.FormatFloat(lat, 'f', 2, 64),
+		strconv.FormatFloat(long, 'f', 2, 64))
 }
@@ -0,0 +1,178 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package protocol_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cosnicolaou/automation/net/netutil"
+	"github.com/cosnicolaou/automation/net/streamconn"
+	"github.com/cosnicolaou/lutron/internal/testutil"
+	"github.com/cosnicolaou/lutron/protocol"
+)
+
+func TestFormatTimeZone(t *testing.T) {
+	for _, tc := range []struct {
+		offset time.Duration
+		want   string
+	}{
+		{0, "+0:00"},
+		{-8 * time.Hour, "-8:00"},
+		{5*time.Hour + 30*time.Minute, "+5:30"},
+		{-(9*time.Hour + 30*time.Minute), "-9:30"},
+		{13 * time.Hour, "+13:00"},
+	} {
+		if got, want := protocol.FormatTimeZone(tc.offset), tc.want; got != want {
+			t.Errorf("%v: got %v, want %v", tc.offset, got, want)
+		}
+	}
+}
+
+func TestSetSystem(t *testing.T) {
+	ctx := context.Background()
+
+	mock := testutil.NewMockTransport(testing.Verbose())
+	mock.SetResponse("#SYSTEM,1,20:21:47\r\n", "QNET> ")
+	mock.SetResponse("#SYSTEM,2,11/07/2024\r\n", "QNET> ")
+	mock.SetResponse("#SYSTEM,5,-8:00\r\n", "QNET> ")
+	mock.SetResponse("#SYSTEM,4,37.42,-122.08\r\n", "QNET> ")
+	mock.SetResponse("?SYSTEM,5\r\n", "~SYSTEM,5,-8:00\r\nQNET> ")
+
+	mgr := &streamconn.SessionManager{}
+	s := mgr.New(mock, netutil.NewIdleTimer(10))
+	defer s.Release()
+
+	when := time.Date(2024, 11, 7, 20, 21, 47, 0, time.UTC)
+	if err := protocol.SetTime(ctx, s, when); err != nil {
+		t.Fatal(err)
+	}
+	if err := protocol.SetDate(ctx, s, when); err != nil {
+		t.Fatal(err)
+	}
+	if err := protocol.SetTimeZone(ctx, s, -8*time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := protocol.SetLatLong(ctx, s, 37.4219, -122.0841); err != nil {
+		t.Fatal(err)
+	}
+	if err := protocol.SetLatLong(ctx, s, 91, 0); err == nil {
+		t.Errorf("expected an error for an invalid latitude")
+	}
+	loc, err := protocol.GetTimeZone(ctx, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, offset := when.In(loc).Zone(); offset != -8*60*60 {
+		t.Errorf("got %v, want %v", offset, -8*60*60)
+	}
+}
+
+func TestNormalizeTimeZone(t *testing.T) {
+	for _, tc := range []struct {
+		tz   string
+		want string
+	}{
+		{"", "+00:00"},
+		{"-8:00", "-08:00"},
+		{"-08:00", "-08:00"},
+		{"+5:30", "+05:30"},
+		{"5:30", "+05:30"},
+		{"+10:00", "+10:00"},
+		{"-10:00", "-10:00"},
+		{"0:00", "+00:00"},
+		{"-3:30", "-03:30"},
+		{"+12:45", "+12:45"},
+		{"+0530", "+05:30"},
+		{"-8", "-08:00"},
+		{"bad", "bad"},
+		{"+5:3", "+5:3"},
+	} {
+		if got, want := protocol.NormalizeTimeZone(tc.tz), tc.want; got != want {
+			t.Errorf("%q: got %v, want %v", tc.tz, got, want)
+		}
+	}
+	for _, tc := range []struct {
+		tz     string
+		offset int
+	}{
+		{"", 0},
+		{"-8:00", -8 * 3600},
+		{"+5:30", 5*3600 + 30*60},
+		{"-3:30", -(3*3600 + 30*60)},
+		{"+12:45", 12*3600 + 45*60},
+	} {
+		loc, err := protocol.ParseTimeZone(tc.tz)
+		if err != nil {
+			t.Errorf("%q: %v", tc.tz, err)
+			continue
+		}
+		if _, offset := time.Date(2024, 1, 1, 0, 0, 0, 0, loc).Zone(); offset != tc.offset {
+			t.Errorf("%q: got %v, want %v", tc.tz, offset, tc.offset)
+		}
+	}
+	if _, err := protocol.ParseTimeZone("bad"); err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func TestSunriseSunset(t *testing.T) {
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		tz     string
+		offset int
+	}{
+		{"-8:00", -8 * 3600},
+		{"+5:30", 5*3600 + 30*60},
+		{"+10:00", 10 * 3600},
+	} {
+		mock := testutil.NewMockTransport(testing.Verbose())
+		mock.SetResponse("?SYSTEM,2\r\n", "~SYSTEM,2,11/17/2024\r\nQNET> ")
+		mock.SetResponse("?SYSTEM,1\r\n", "~SYSTEM,1,12:01:02\r\nQNET> ")
+		mock.SetResponse("?SYSTEM,5\r\n", "~SYSTEM,5,"+tc.tz+"\r\nQNET> ")
+		mock.SetResponse("?SYSTEM,7\r\n", "~SYSTEM,7,06:51:10\r\nQNET> ")
+		mock.SetResponse("?SYSTEM,6\r\n", "~SYSTEM,6,16:55:03\r\nQNET> ")
+		mock.SetResponse("?SYSTEM,4\r\n", "~SYSTEM,4,37.42,-122.08\r\nQNET> ")
+		mock.SetResponse("?SYSTEM,8\r\n", "OS Firmware Revision = 8.52\r\nQNET> ")
+
+		mgr := &streamconn.SessionManager{}
+		s := mgr.New(mock, netutil.NewIdleTimer(10))
+
+		loc := time.FixedZone("", tc.offset)
+		rise, set, err := protocol.GetSunriseSunset(ctx, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := time.Date(2024, 11, 17, 6, 51, 10, 0, loc); !rise.Equal(want) {
+			t.Errorf("%v: got %v, want %v", tc.tz, rise, want)
+		}
+		if want := time.Date(2024, 11, 17, 16, 55, 3, 0, loc); !set.Equal(want) {
+			t.Errorf("%v: got %v, want %v", tc.tz, set, want)
+		}
+		if _, offset := rise.Zone(); offset != tc.offset {
+			t.Errorf("%v: got %v, want %v", tc.tz, offset, tc.offset)
+		}
+
+		info, err := protocol.GetSystemInfo(ctx, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := time.Date(2024, 11, 17, 12, 1, 2, 0, loc); !info.Time.Equal(want) {
+			t.Errorf("%v: got %v, want %v", tc.tz, info.Time, want)
+		}
+		if !info.Sunrise.Equal(rise) || !info.Sunset.Equal(set) {
+			t.Errorf("%v: got %v %v, want %v %v", tc.tz, info.Sunrise, info.Sunset, rise, set)
+		}
+		if info.Latitude != 37.42 || info.Longitude != -122.08 {
+			t.Errorf("%v: got %v %v", tc.tz, info.Latitude, info.Longitude)
+		}
+		if got, want := info.OSRevision, "8.52"; got != want {
+			t.Errorf("%v: got %v, want %v", tc.tz, got, want)
+		}
+		s.Release()
+	}
+}
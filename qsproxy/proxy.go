@@ -0,0 +1,438 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+// Package qsproxy provides a telnet server that emulates a QS processor's
+// integration interface for third-party clients. All clients are
+// multiplexed onto a single upstream connection to the processor and
+// monitoring events are fanned out to all clients.
+package qsproxy
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"cloudeng.io/cmdutil/keystore"
+	"cloudeng.io/logging/ctxlog"
+	"github.com/cosnicolaou/lutron/homeworks"
+	"github.com/cosnicolaou/lutron/protocol"
+)
+
+// Upstream represents the processor that client commands are relayed
+// to and from which monitoring events are obtained. It is implemented
+// by homeworks.QSProcessor, note that events are only published whilst
+// QSProcessor.Monitor is running.
+type Upstream interface {
+	Exec(ctx context.Context, line string) (string, error)
+	Events() *homeworks.EventHub
+}
+
+// ClientConfig represents the configuration for a single client.
+type ClientConfig struct {
+	// KeyID is the ID of the keystore entry that contains the
+	// user and password (token) for the client.
+	KeyID string `yaml:"key_id"`
+	// Allow is the list of commands the client may issue, each
+	// entry is matched as a case-insensitive prefix of the command,
+	// eg. "?OUTPUT" or "#OUTPUT,5,". All commands are allowed if
+	// Allow is empty. Commands that affect the connection to the
+	// processor, which is shared by all clients, such as #RESET,
+	// #ETHERNET and #PROMPTOFF, are never relayed regardless of Allow
+	// and LOGOUT closes the client's own connection.
+	Allow []string `yaml:"allow"`
+}
+
+const (
+	prompt                   = "QNET> "
+	defaultEventBuffer       = 64
+	maxLoginAttempts         = 3
+	defaultLoginFailureDelay = time.Second
+	defaultLoginTimeout      = 30 * time.Second
+	// maxLineLength is the longest command line accepted from a client.
+	maxLineLength = 256
+)
+
+// Option represents an option to NewServer.
+type Option func(*Server)
+
+// WithLoginFailureDelay sets the delay after each failed login attempt,
+// it defaults to one second.
+func WithLoginFailureDelay(d time.Duration) Option {
+	return func(s *Server) {
+		s.loginDelay = d
+	}
+}
+
+// WithLoginTimeout sets the time allowed for a client to login, it
+// defaults to 30 seconds.
+func WithLoginTimeout(d time.Duration) Option {
+	return func(s *Server) {
+		s.loginTimeout = d
+	}
+}
+
+// Server is a telnet server that emulates a QS processor.
+type Server struct {
+	upstream     Upstream
+	clients      []ClientConfig
+	buffer       int
+	loginDelay   time.Duration
+	loginTimeout time.Duration
+}
+
+// NewServer returns a new server that relays commands to upstream for
+// the specified clients. Monitoring events are only fanned out to
+// clients whilst upstream is publishing them, ie. for a
+// homeworks.QSProcessor, QSProcessor.Monitor must be running.
+// Connections are closed after three failed login attempts or if the
+// client fails to login within the login timeout.
+func NewServer(upstream Upstream, clients []ClientConfig, opts ...Option) *Server {
+	s := &Server{
+		upstream:     upstream,
+		clients:      clients,
+		buffer:       defaultEventBuffer,
+		loginDelay:   defaultLoginFailureDelay,
+		loginTimeout: defaultLoginTimeout,
+	}
+	for _, fn := range opts {
+		fn(s)
+	}
+	return s
+}
+
+// Serve accepts connections on the supplied listener until the context
+// is canceled or the listener is closed.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return ctx.Err()
+			}
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer conn.Close()
+			cctx := ctxlog.WithAttributes(ctx, "qsproxy", conn.RemoteAddr().String())
+			if err := s.serveConn(cctx, conn); err != nil && !errors.Is(err, io.EOF) {
+				ctxlog.Info(cctx, "qsproxy: connection closed", "err", err)
+			}
+		}()
+	}
+}
+
+// client represents a single logged in client connection.
+type client struct {
+	mu         sync.Mutex
+	w          io.Writer
+	cfg        ClientConfig
+	monitoring map[protocol.MonitoringType]bool
+}
+
+func (c *client) write(s string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err := io.WriteString(c.w, s)
+	return err
+}
+
+func (c *client) allowed(line string) bool {
+	if len(c.cfg.Allow) == 0 {
+		return true
+	}
+	upper := strings.ToUpper(line)
+	for _, a := range c.cfg.Allow {
+		if strings.HasPrefix(upper, strings.ToUpper(a)) {
+			return true
+		}
+	}
+	return false
+}
+
+// stripTelnet removes telnet option negotiation sequences, ie.
+// IAC <cmd> <option>, from line.
+func stripTelnet(line string) string {
+	if !strings.Contains(line, "\xff") {
+		return line
+	}
+	var out strings.Builder
+	for i := 0; i < len(line); i++ {
+		if line[i] == 0xff {
+			i += 2
+			continue
+		}
+		out.WriteByte(line[i])
+	}
+	return out.String()
+}
+
+var (
+	errInvalidLine = errors.New("line contains control characters")
+	errLineTooLong = errors.New("line too long")
+	errLoginFailed = errors.New("too many failed login attempts")
+)
+
+// readLine returns the next line with any telnet sequences and
+// surrounding white space removed. Lines that contain any other control
+// characters, including an embedded carriage return, are rejected with
+// errInvalidLine since the upstream processor would treat a carriage
+// return as a command terminator and hence allow a second, unchecked,
+// command to be smuggled in. Lines longer than rd's buffer, which must
+// be of size maxLineLength, fail with errLineTooLong.
+func readLine(rd *bufio.Reader) (string, error) {
+	buf, err := rd.ReadSlice('\n')
+	if errors.Is(err, bufio.ErrBufferFull) {
+		return "", errLineTooLong
+	}
+	if err != nil {
+		return "", err
+	}
+	line := strings.TrimSpace(stripTelnet(string(buf)))
+	for i := 0; i < len(line); i++ {
+		if line[i] < 0x20 || line[i] == 0x7f {
+			return "", errInvalidLine
+		}
+	}
+	return line, nil
+}
+
+func (s *Server) authenticate(ctx context.Context, user, pass string) (ClientConfig, bool) {
+	for _, cfg := range s.clients {
+		keys := keystore.AuthFromContextForID(ctx, cfg.KeyID)
+		if len(keys.User) == 0 || keys.User != user {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(keys.Token), []byte(pass)) == 1 {
+			return cfg, true
+		}
+	}
+	return ClientConfig{}, false
+}
+
+// login prompts for a user and password, returning an error after
+// maxLoginAttempts failures. Each failure is followed by a delay
+// to slow down password guessing.
+func (s *Server) login(ctx context.Context, rd *bufio.Reader, w io.Writer) (ClientConfig, error) {
+	for range maxLoginAttempts {
+		if _, err := io.WriteString(w, "login: "); err != nil {
+			return ClientConfig{}, err
+		}
+		user, uerr := readLine(rd)
+		if uerr != nil && !errors.Is(uerr, errInvalidLine) {
+			return ClientConfig{}, uerr
+		}
+		if _, err := io.WriteString(w, "password: "); err != nil {
+			return ClientConfig{}, err
+		}
+		pass, perr := readLine(rd)
+		if perr != nil && !errors.Is(perr, errInvalidLine) {
+			return ClientConfig{}, perr
+		}
+		if uerr == nil && perr == nil {
+			if cfg, ok := s.authenticate(ctx, user, pass); ok {
+				return cfg, nil
+			}
+		}
+		ctxlog.Info(ctx, "qsproxy: bad login", "user", user)
+		select {
+		case <-ctx.Done():
+			return ClientConfig{}, ctx.Err()
+		case <-time.After(s.loginDelay):
+		}
+		if _, err := io.WriteString(w, "bad login\r\n"); err != nil {
+			return ClientConfig{}, err
+		}
+	}
+	return ClientConfig{}, errLoginFailed
+}
+
+func (s *Server) serveConn(ctx context.Context, conn net.Conn) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		// Unblock any pending reads when the server is stopped.
+		<-ctx.Done()
+		conn.Close()
+	}()
+	rd := bufio.NewReaderSize(conn, maxLineLength)
+	if err := conn.SetReadDeadline(time.Now().Add(s.loginTimeout)); err != nil {
+		return err
+	}
+	cfg, err := s.login(ctx, rd, conn)
+	if err != nil {
+		return err
+	}
+	if err := conn.SetReadDeadline(time.Time{}); err != nil {
+		return err
+	}
+	c := &client{w: conn, cfg: cfg, monitoring: map[protocol.MonitoringType]bool{}}
+	for _, t := range protocol.MonitoringTypes() {
+		c.monitoring[t] = true
+	}
+	c.monitoring[protocol.MonitorAll] = true
+	sub := s.upstream.Events().Subscribe(ctx, s.buffer)
+	go s.forwardEvents(ctx, c, sub)
+
+	if err := c.write("\r\n" + prompt); err != nil {
+		return err
+	}
+	for {
+		line, err := readLine(rd)
+		if errors.Is(err, errInvalidLine) {
+			ctxlog.Info(ctx, "qsproxy: invalid command", "err", err)
+			if err := c.write("~ERROR,1\r\n" + prompt); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if strings.EqualFold(line, "LOGOUT") {
+			// Never relayed since it would close the connection
+			// shared by all clients.
+			return nil
+		}
+		if err := c.write(s.handle(ctx, c, line) + prompt); err != nil {
+			return err
+		}
+	}
+}
+
+// handle returns the response, without a trailing prompt, for the
+// supplied command line.
+func (s *Server) handle(ctx context.Context, c *client, line string) string {
+	if len(line) == 0 {
+		return ""
+	}
+	if !c.allowed(line) {
+		ctxlog.Info(ctx, "qsproxy: command not allowed", "command", line)
+		return "~ERROR,6\r\n"
+	}
+	cmd := strings.ToUpper(line)
+	if strings.HasPrefix(cmd[1:], "MONITORING") {
+		// Monitoring is controlled per-client and is never relayed
+		// since it would affect all clients.
+		return c.monitoringCommand(cmd)
+	}
+	if denied(cmd) {
+		ctxlog.Info(ctx, "qsproxy: command denied", "command", line)
+		return "~ERROR,6\r\n"
+	}
+	resp, err := s.upstream.Exec(ctx, line)
+	if err != nil {
+		ctxlog.Info(ctx, "qsproxy: upstream failed", "command", line, "err", err)
+		return "~ERROR,6\r\n"
+	}
+	return resp
+}
+
+// deniedCommands are the commands that affect the connection to the
+// processor, or the processor as a whole, and hence are never relayed
+// on behalf of clients.
+var deniedCommands = []string{"LOGIN", "LOGOUT", "RESET", "ETHERNET", "PROMPTON", "PROMPTOFF"}
+
+// denied returns true if the upper-cased command is one of deniedCommands.
+func denied(cmd string) bool {
+	name, _, _ := strings.Cut(strings.TrimLeft(cmd, "#?"), ",")
+	return slices.Contains(deniedCommands, name)
+}
+
+// monitoringCommand handles #MONITORING,<type>,<1|2> and
+// ?MONITORING,<type> commands for the client.
+func (c *client) monitoringCommand(cmd string) string {
+	parts := strings.Split(cmd[1:], ",")
+	if len(parts) < 2 {
+		return "~ERROR,1\r\n"
+	}
+	n, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return "~ERROR,5\r\n"
+	}
+	typ := protocol.MonitoringType(n)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.monitoring[typ]; !ok {
+		return "~ERROR,4\r\n"
+	}
+	switch cmd[0] {
+	case '?':
+		state := 2
+		if c.monitoring[typ] {
+			state = 1
+		}
+		return fmt.Sprintf("~MONITORING,%v,%v\r\n", n, state)
+	case '#':
+		if len(parts) != 3 || (parts[2] != "1" && parts[2] != "2") {
+			return "~ERROR,4\r\n"
+		}
+		on := parts[2] == "1"
+		if typ == protocol.MonitorAll {
+			for t := range c.monitoring {
+				c.monitoring[t] = on
+			}
+		}
+		c.monitoring[typ] = on
+		return fmt.Sprintf("~MONITORING,%v,%v\r\n", n, parts[2])
+	}
+	return "~ERROR,6\r\n"
+}
+
+// monitoringTypeFor returns the monitoring type that controls whether
+// the event is forwarded to a client, events that are not covered by
+// a specific monitoring type are controlled by MonitorAll.
+func monitoringTypeFor(ev protocol.Event) protocol.MonitoringType {
+	switch ev.Type() {
+	case protocol.EventButton:
+		return protocol.MonitorButton
+	case protocol.EventLED:
+		return protocol.MonitorLED
+	case protocol.EventLevel:
+		return protocol.MonitorZone
+	case protocol.EventOccupancy:
+		return protocol.MonitorOccupancy
+	case protocol.EventSysvar:
+		return protocol.MonitorSysvar
+	case protocol.EventHVAC:
+		return protocol.MonitorHVAC
+	}
+	return protocol.MonitorAll
+}
+
+func (c *client) wants(ev protocol.Event) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.monitoring[monitoringTypeFor(ev)]
+}
+
+func (s *Server) forwardEvents(ctx context.Context, c *client, sub *homeworks.Subscription) {
+	for ev := range sub.Events() {
+		if n := sub.Dropped(); n > 0 {
+			ctxlog.Info(ctx, "qsproxy: dropped events", "dropped", n)
+		}
+		if !c.wants(ev) {
+			continue
+		}
+		if err := c.write(ev.String() + "\r\n"); err != nil {
+			return
+		}
+	}
+}
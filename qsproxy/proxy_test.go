@@ -0,0 +1,256 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package qsproxy_test
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cloudeng.io/cmdutil/keystore"
+	"github.com/cosnicolaou/lutron/homeworks"
+	"github.com/cosnicolaou/lutron/protocol"
+	"github.com/cosnicolaou/lutron/qsproxy"
+)
+
+type upstream struct {
+	mu        sync.Mutex
+	hub       *homeworks.EventHub
+	responses map[string]string
+	sent      []string
+}
+
+func (u *upstream) Exec(_ context.Context, line string) (string, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.sent = append(u.sent, line)
+	return u.responses[line], nil
+}
+
+func (u *upstream) Events() *homeworks.EventHub {
+	return u.hub
+}
+
+type testClient struct {
+	t    *testing.T
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+func dial(t *testing.T, addr string) *testClient {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &testClient{t: t, conn: conn, rd: bufio.NewReader(conn)}
+}
+
+// expect reads until suffix is seen and returns all of the data read.
+func (c *testClient) expect(suffix string) string {
+	c.t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second)) //nolint:errcheck
+	var buf []byte
+	for !strings.HasSuffix(string(buf), suffix) {
+		b, err := c.rd.ReadByte()
+		if err != nil {
+			c.t.Fatalf("waiting for %q, got %q: %v", suffix, buf, err)
+		}
+		buf = append(buf, b)
+	}
+	return string(buf)
+}
+
+func (c *testClient) send(line string) {
+	c.t.Helper()
+	if _, err := c.conn.Write([]byte(line + "\r\n")); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// expectClosed waits for the server to close the connection, which may
+// be reported as a reset if the client's data was never read.
+func (c *testClient) expectClosed() {
+	c.t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second)) //nolint:errcheck
+	_, err := io.ReadAll(c.rd)
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		c.t.Errorf("expected connection to be closed: %v", err)
+	}
+}
+
+func (c *testClient) login(user, pass string) {
+	c.t.Helper()
+	c.expect("login: ")
+	c.send(user)
+	c.expect("password: ")
+	c.send(pass)
+	c.expect("QNET> ")
+}
+
+func TestProxy(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx = keystore.ContextWithAuth(ctx, keystore.Keys{
+		"full":     {ID: "full", User: "admin", Token: "s3cret"},
+		"readonly": {ID: "readonly", User: "viewer", Token: "pw"},
+	})
+	up := &upstream{
+		hub: homeworks.NewEventHub(),
+		responses: map[string]string{
+			"?OUTPUT,5,1":    "~OUTPUT,5,1,50.00\r\n",
+			"#OUTPUT,5,1,75": "",
+		},
+	}
+	srv := qsproxy.NewServer(up, []qsproxy.ClientConfig{
+		{KeyID: "full"},
+		{KeyID: "readonly", Allow: []string{"?output"}},
+	}, qsproxy.WithLoginFailureDelay(time.Millisecond))
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Serve(ctx, ln) }()
+
+	full := dial(t, ln.Addr().String())
+	full.expect("login: ")
+	full.send("admin")
+	full.expect("password: ")
+	full.send("wrong")
+	full.expect("bad login\r\n")
+	full.login("admin", "s3cret")
+
+	ro := dial(t, ln.Addr().String())
+	ro.login("viewer", "pw")
+
+	full.send("?OUTPUT,5,1")
+	if got, want := full.expect("QNET> "), "~OUTPUT,5,1,50.00\r\nQNET> "; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	full.send("#OUTPUT,5,1,75")
+	if got, want := full.expect("QNET> "), "QNET> "; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	ro.send("?OUTPUT,5,1")
+	if got, want := ro.expect("QNET> "), "~OUTPUT,5,1,50.00\r\nQNET> "; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	ro.send("#OUTPUT,5,1,75")
+	if got, want := ro.expect("QNET> "), "~ERROR,6\r\nQNET> "; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	// An embedded carriage return must not be used to smuggle in a
+	// command that is not allowed.
+	ro.send("?OUTPUT,5,1\r#OUTPUT,9,1,100")
+	if got, want := ro.expect("QNET> "), "~ERROR,1\r\nQNET> "; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// Commands that affect the shared upstream connection are never
+	// relayed, even for clients with no allow list.
+	for _, cmd := range []string{"#RESET,0", "#prompton", "?ETHERNET,0"} {
+		full.send(cmd)
+		if got, want := full.expect("QNET> "), "~ERROR,6\r\nQNET> "; got != want {
+			t.Errorf("%v: got %q, want %q", cmd, got, want)
+		}
+	}
+
+	// Monitoring commands are handled per-client and never relayed.
+	full.send("#MONITORING,5,2")
+	if got, want := full.expect("QNET> "), "~MONITORING,5,2\r\nQNET> "; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	full.send("?MONITORING,5")
+	if got, want := full.expect("QNET> "), "~MONITORING,5,2\r\nQNET> "; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	for up.hub.Len() != 2 {
+		time.Sleep(time.Millisecond)
+	}
+	for _, line := range []string{"~OUTPUT,5,1,75.00", "~DEVICE,10,3,3"} {
+		ev, err := protocol.ParseEvent(line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		up.hub.Publish(ev)
+	}
+	// The zone event is not sent to the client that disabled zone monitoring.
+	if got, want := full.expect("\r\n"), "~DEVICE,10,3,3\r\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := ro.expect("~DEVICE,10,3,3\r\n"), "~OUTPUT,5,1,75.00\r\n~DEVICE,10,3,3\r\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	up.mu.Lock()
+	if got, want := strings.Join(up.sent, " "), "?OUTPUT,5,1 #OUTPUT,5,1,75 ?OUTPUT,5,1"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	up.mu.Unlock()
+
+	// LOGOUT closes the client's own connection only.
+	ro.send("logout")
+	ro.expectClosed()
+	full.send("?OUTPUT,5,1")
+	full.expect("~OUTPUT,5,1,50.00\r\nQNET> ")
+
+	// The connection is closed after too many failed logins.
+	bad := dial(t, ln.Addr().String())
+	for range 3 {
+		bad.expect("login: ")
+		bad.send("admin")
+		bad.expect("password: ")
+		bad.send("guess")
+		bad.expect("bad login\r\n")
+	}
+	bad.expectClosed()
+
+	cancel()
+	if err := <-errCh; err != context.Canceled {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestProxyLoginLimits(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	up := &upstream{hub: homeworks.NewEventHub()}
+	srv := qsproxy.NewServer(up, nil, qsproxy.WithLoginTimeout(50*time.Millisecond))
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Serve(ctx, ln) }()
+
+	// Clients that never complete a line are disconnected.
+	idle := dial(t, ln.Addr().String())
+	idle.expect("login: ")
+	if _, err := idle.conn.Write([]byte("admin")); err != nil {
+		t.Fatal(err)
+	}
+	idle.expectClosed()
+
+	// As are those that send overly long lines.
+	long := dial(t, ln.Addr().String())
+	long.expect("login: ")
+	if _, err := long.conn.Write([]byte(strings.Repeat("x", 4096))); err != nil {
+		t.Fatal(err)
+	}
+	long.expectClosed()
+
+	cancel()
+	if err := <-errCh; err != context.Canceled {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+// Package transcript provides support for recording the bytes sent and
+// received over a streamconn.Transport so that sessions with real
+// hardware can be captured and replayed in tests.
+//
+// A transcript is a text file with one entry per line of the form:
+//
+//	<RFC3339Nano timestamp> <direction> <Go quoted string>
+//
+// where direction is one of send, sensitive, recv or error. The
+// payload of sensitive entries is always recorded as "***".
+package transcript
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cosnicolaou/automation/net/streamconn"
+)
+
+// Direction represents the direction of a recorded entry.
+type Direction string
+
+const (
+	Send      Direction = "send"
+	Sensitive Direction = "sensitive"
+	Recv      Direction = "recv"
+	Error     Direction = "error"
+)
+
+// SensitiveMask is recorded in place of sensitive payloads.
+const SensitiveMask = "***"
+
+// Entry represents a single recorded exchange.
+type Entry struct {
+	Time      time.Time
+	Direction Direction
+	Data      string
+}
+
+func (e Entry) String() string {
+	return e.Time.Format(time.RFC3339Nano) + " " + string(e.Direction) + " " + strconv.Quote(e.Data)
+}
+
+// ParseEntry parses a single line of a transcript.
+func ParseEntry(line string) (Entry, error) {
+	ts, rest, ok := strings.Cut(line, " ")
+	if !ok {
+		return Entry{}, fmt.Errorf("malformed entry: %q", line)
+	}
+	dir, data, ok := strings.Cut(rest, " ")
+	if !ok {
+		return Entry{}, fmt.Errorf("malformed entry: %q", line)
+	}
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return Entry{}, fmt.Errorf("malformed timestamp: %q: %w", line, err)
+	}
+	switch d := Direction(dir); d {
+	case Send, Sensitive, Recv, Error:
+	default:
+		return Entry{}, fmt.Errorf("unknown direction: %q: %q", d, line)
+	}
+	unq, err := strconv.Unquote(data)
+	if err != nil {
+		return Entry{}, fmt.Errorf("malformed data: %q: %w", line, err)
+	}
+	return Entry{Time: t, Direction: Direction(dir), Data: unq}, nil
+}
+
+// Read reads a transcript, blank lines and lines starting with # are ignored.
+func Read(rd io.Reader) ([]Entry, error) {
+	var entries []Entry
+	sc := bufio.NewScanner(rd)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		e, err := ParseEntry(line)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, sc.Err()
+}
+
+// ReadFile reads a transcript from the named file.
+func ReadFile(filename string) ([]Entry, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// Recorder is a streamconn.Transport that records all of the data
+// sent and received over the transport that it wraps.
+type Recorder struct {
+	conn streamconn.Transport
+	mu   sync.Mutex
+	out  io.Writer
+	now  func() time.Time
+}
+
+// NewRecorder returns a Recorder that wraps conn and writes a transcript
+// to out. If out implements io.Closer it will be closed when the Recorder
+// is closed.
+func NewRecorder(conn streamconn.Transport, out io.Writer) *Recorder {
+	return &Recorder{conn: conn, out: out, now: time.Now}
+}
+
+func (r *Recorder) record(dir Direction, data string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e := Entry{Time: r.now(), Direction: dir, Data: data}
+	fmt.Fprintln(r.out, e.String())
+}
+
+// Send implements streamconn.Transport.
+func (r *Recorder) Send(ctx context.Context, buf []byte) (int, error) {
+	r.record(Send, string(buf))
+	return r.conn.Send(ctx, buf)
+}
+
+// SendSensitive implements streamconn.Transport, the contents of buf
+// are not recorded.
+func (r *Recorder) SendSensitive(ctx context.Context, buf []byte) (int, error) {
+	r.record(Sensitive, SensitiveMask)
+	return r.conn.SendSensitive(ctx, buf)
+}
+
+// ReadUntil implements streamconn.Transport.
+func (r *Recorder) ReadUntil(ctx context.Context, expected []string) ([]byte, error) {
+	buf, err := r.conn.ReadUntil(ctx, expected)
+	if len(buf) > 0 || err == nil {
+		r.record(Recv, string(buf))
+	}
+	if err != nil {
+		r.record(Error, err.Error())
+	}
+	return buf, err
+}
+
+// Close implements streamconn.Transport.
+func (r *Recorder) Close(ctx context.Context) error {
+	err := r.conn.Close(ctx)
+	if c, ok := r.out.(io.Closer); ok {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
@@ -0,0 +1,103 @@
+// Copyright 2025 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package transcript_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/cosnicolaou/automation/net/netutil"
+	"github.com/cosnicolaou/automation/net/streamconn"
+	"github.com/cosnicolaou/lutron/internal/testutil"
+	"github.com/cosnicolaou/lutron/protocol"
+	"github.com/cosnicolaou/lutron/transcript"
+)
+
+func runSession(ctx context.Context, t *testing.T, conn streamconn.Transport) string {
+	t.Helper()
+	mgr := &streamconn.SessionManager{}
+	s := mgr.New(conn, netutil.NewIdleTimer(10))
+	defer s.Release()
+	if err := protocol.QSLogin(ctx, s, "admin", "s3cret"); err != nil {
+		t.Fatal(err)
+	}
+	v, err := protocol.GetVersion(ctx, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestRecordReplay(t *testing.T) {
+	ctx := context.Background()
+
+	mock := testutil.NewMockTransport(testing.Verbose())
+	mock.SetResponse("login: ", "\x00login: ")
+	mock.SetResponse("admin\r\n", "password: ")
+	mock.SetResponse("s3cret\r\n", "\r\nQNET> ")
+	mock.SetResponse("?SYSTEM,8\r\n", "\x00OS Firmware Revision = 8.52\r\nQNET> ")
+	if _, err := mock.Send(ctx, []byte("login: ")); err != nil {
+		t.Fatal(err)
+	}
+
+	out := &bytes.Buffer{}
+	recorded := runSession(ctx, t, transcript.NewRecorder(mock, out))
+
+	if strings.Contains(out.String(), "s3cret") {
+		t.Errorf("sensitive data was recorded: %v", out.String())
+	}
+
+	entries, err := transcript.Read(bytes.NewReader(out.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dirs []transcript.Direction
+	for _, e := range entries {
+		dirs = append(dirs, e.Direction)
+	}
+	if got, want := len(dirs), 7; got != want {
+		t.Fatalf("got %v, want %v: %v", got, want, dirs)
+	}
+	if got, want := dirs[3], transcript.Sensitive; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := entries[6].Data, "\x00OS Firmware Revision = 8.52\r\nQNET> "; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	replay := testutil.NewReplayTransport(entries)
+	if got, want := runSession(ctx, t, replay), recorded; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := replay.Remaining(), 0; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	replay = testutil.NewReplayTransport(entries)
+	if _, err := replay.ReadUntil(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := replay.Send(ctx, []byte("root\r\n")); !errors.Is(err, testutil.ErrReplayMismatch) {
+		t.Errorf("unexpected or missing error: %v", err)
+	}
+}
+
+func TestParseEntry(t *testing.T) {
+	for _, tc := range []string{
+		"",
+		"2024-11-17T20:21:45Z",
+		"2024-11-17T20:21:45Z recv",
+		"2024-11-17T20:21:45Z unknown \"x\"",
+		"not-a-time recv \"x\"",
+		"2024-11-17T20:21:45Z recv unquoted",
+	} {
+		if _, err := transcript.ParseEntry(tc); err == nil {
+			t.Errorf("%q: expected an error", tc)
+		}
+	}
+}